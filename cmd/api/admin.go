@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// createAdmin creates the first admin, an activated user with a library of their
+// own and the admin permission. Admins grant everyone else their permissions, so
+// without one nobody can.
+func (app *application) createAdmin(name, email, password string) error {
+	user := &data.User{
+		Name:      name,
+		Email:     email,
+		Activated: true,
+	}
+
+	v := validator.New()
+
+	data.ValidatePasswordPlaintext(v, password)
+	if !v.Valid() {
+		return fmt.Errorf("ADMIN_PASSWORD %s", v.Errors["password"])
+	}
+
+	err := user.Password.Set(password)
+	if err != nil {
+		return err
+	}
+
+	if data.ValidateUser(v, user); !v.Valid() {
+		return fmt.Errorf("invalid admin: %v", v.Errors)
+	}
+
+	err = app.models.RunInTx(func(tx data.Models) error {
+		libraryId, err := tx.Library.Insert(&data.Library{Name: user.Name + "'s library"})
+		if err != nil {
+			return err
+		}
+		user.LibraryID = int64(libraryId)
+
+		err = tx.User.Insert(user)
+		if err != nil {
+			return err
+		}
+
+		return tx.Permission.AddForUser(user.ID, data.PermissionAdmin)
+	})
+	if errors.Is(err, data.ErrDuplicateEmail) {
+		return fmt.Errorf("a user with the email address %s already exists", email)
+	}
+
+	return err
+}
@@ -56,11 +56,32 @@ func (app *application) getAuthorHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	author, err := app.models.Author.GetAuthor(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	books, err := app.models.Book.GetAuthorBooks(id, app.contextGetUser(r))
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	dateLayout := "02/01/2006"
+	for _, roleBooks := range books {
+		for _, b := range roleBooks {
+			b.DateAdded = b.CreatedAt.UTC().Format(dateLayout)
+			b.DateUpdated = b.UpdatedAt.UTC().Format(dateLayout)
+		}
+	}
+
+	author.BooksByRole = books
+
 	err = app.writeToJSON(w, http.StatusOK, envelope{"author": author}, nil)
 	if err != nil {
 		if err != nil {
@@ -74,6 +95,16 @@ func (app *application) getAuthorHandler(w http.ResponseWriter, r *http.Request)
 func (app *application) getAuthorsHandler(w http.ResponseWriter, r *http.Request) {
 
 	authors, err := app.models.Author.GetAuthors()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	numberOfBooks, err := app.models.Author.GetAuthorsNumberOfBooks(app.contextGetUser(r).LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
 	dateLayout := "02/01/2006"
 	for _, author := range authors {
@@ -81,14 +112,7 @@ func (app *application) getAuthorsHandler(w http.ResponseWriter, r *http.Request
 		author.DateAdded = author.CreatedAt.UTC().Format(dateLayout)
 		author.DateUpdated = author.UpdatedAt.UTC().Format(dateLayout)
 
-		numberOfBooks, _ := app.models.Author.GetAuthorNumberOfBooks(author.AuthorID)
-
-		author.AuthorBooks = numberOfBooks
-	}
-
-	if err != nil {
-		app.notFoundResponse(w, r)
-		return
+		author.AuthorBooks = numberOfBooks[author.AuthorID]
 	}
 
 	sort.Slice(authors, func(i, j int) bool {
@@ -114,6 +138,10 @@ func (app *application) deleteAuthorHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if !app.checkLibraryOnly(w, r, app.models.Author.UsedOutsideLibrary, toInt64s(input.ID)) {
+		return
+	}
+
 	for _, id := range input.ID {
 		err = app.models.Author.DeleteAuthor(int64(id))
 	}
@@ -203,6 +231,10 @@ func (app *application) updateAuthorHandler(w http.ResponseWriter, r *http.Reque
 	// 	return
 	// }
 
+	if !app.checkLibraryOnly(w, r, app.models.Author.UsedOutsideLibrary, []int64{author.AuthorID}) {
+		return
+	}
+
 	err = app.models.Author.UpdateAuthor(author)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -3,13 +3,18 @@ package main
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/tklara86/book_catalogue/internal/data"
 	"github.com/tklara86/book_catalogue/internal/validator"
 )
 
+// bookSortSafelist are the sort values of the book lists, a leading "-" sorts in
+// descending order.
+var bookSortSafelist = []string{"id", "title", "page_count", "published_date", "status", "created_at", "updated_at", "rating", "-id", "-title", "-page_count", "-published_date", "-status", "-created_at", "-updated_at", "-rating"}
+
 // createBookHandler creates new book
 func (app *application) createBookHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -20,17 +25,27 @@ func (app *application) createBookHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	var input struct {
-		Title         string `json:"title"`
-		Status        int    `json:"status"`
-		StatusID      int    `json:"status_id"`
-		Subtitle      string `json:"subtitle"`
-		Description   string `json:"description"`
-		Image         string `json:"image"`
-		ISBN          string `json:"isbn"`
-		PageCount     int    `json:"page_count"`
-		PublishedDate string `json:"published_date"`
-		Authors       []int  `json:"authors"`
-		Categories    []int  `json:"categories"`
+		WorkID        int64              `json:"work_id"`
+		Title         string             `json:"title"`
+		Status        *int               `json:"status"`
+		StatusID      int                `json:"status_id"`
+		Subtitle      string             `json:"subtitle"`
+		Description   string             `json:"description"`
+		Image         string             `json:"image"`
+		ISBN          string             `json:"isbn"`
+		PageCount     int                `json:"page_count"`
+		PublishedDate string             `json:"published_date"`
+		Authors       []data.Contributor `json:"authors"`
+		Categories    []int              `json:"categories"`
+		Publishers    []int              `json:"publishers"`
+		Series        []data.SeriesEntry `json:"series"`
+		Tags          []string           `json:"tags"`
+		Ownership     string             `json:"ownership"`
+		Format        string             `json:"format"`
+		PurchaseDate  string             `json:"purchase_date"`
+		Price         *float64           `json:"price"`
+		Currency      string             `json:"currency"`
+		Vendor        string             `json:"vendor"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -39,7 +54,11 @@ func (app *application) createBookHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	user := app.contextGetUser(r)
+
 	book := &data.Book{
+		LibraryID:     user.LibraryID,
+		WorkID:        input.WorkID,
 		Title:         input.Title,
 		Subtitle:      input.Subtitle,
 		Description:   input.Description,
@@ -47,61 +66,128 @@ func (app *application) createBookHandler(w http.ResponseWriter, r *http.Request
 		ISBN:          input.ISBN,
 		PageCount:     input.PageCount,
 		PublishedDate: input.PublishedDate,
-		Status:        input.Status,
 		StatusID:      input.StatusID,
-		Authors:       input.Authors,
 		Categories:    input.Categories,
+		Publishers:    input.Publishers,
+		Tags:          data.NormalizeTags(input.Tags),
+		Ownership:     input.Ownership,
+		Format:        input.Format,
+		PurchaseDate:  input.PurchaseDate,
+		Price:         input.Price,
+		Currency:      strings.ToUpper(input.Currency),
+		Vendor:        input.Vendor,
+	}
+
+	if book.Ownership == "" {
+		book.Ownership = data.OwnershipOwned
 	}
 
 	v := validator.New()
 
-	if data.ValidateBook(v, book); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
-		return
+	if input.Status != nil {
+		book.Status = *input.Status
+		data.ValidateStatus(v, book.Status)
 	}
 
-	bookId, err := app.models.Book.Insert(book)
+	data.ValidateContributors(v, input.Authors)
+	data.ValidateSeriesEntries(v, input.Series)
+	data.ValidateTags(v, book.Tags)
+
+	err = app.checkSeriesInLibrary(v, input.Series, user.LibraryID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	bookAuthors := []data.BookAuthor{}
-	for _, authorId := range input.Authors {
-		ba := []data.BookAuthor{
-			{
-				BookId:   int64(bookId),
-				AuthorId: int64(authorId),
-			},
+	// a new edition of an existing work shares the work's authors and categories
+	if input.WorkID != 0 {
+		v.Check(input.Authors == nil && input.Categories == nil, "work_id", "authors and categories belong to the work, update them through one of its editions")
+
+		_, err = app.models.Work.GetWork(input.WorkID, user.LibraryID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("work_id", "must be a work in your library")
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
 		}
-		bookAuthors = append(bookAuthors, ba...)
 	}
 
-	_, err = app.models.Author.InsertBookAuthors(bookAuthors)
-	if err != nil {
+	if data.ValidateBook(v, book); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// the book and its join-table rows are written in one transaction so a
+	// failure part way through doesn't leave an orphaned book behind
+	var bookId int
+	err = app.models.RunInTx(func(tx data.Models) error {
+		if book.WorkID == 0 {
+			workId, err := tx.Work.Insert(&data.Work{
+				LibraryID:   book.LibraryID,
+				Title:       book.Title,
+				Description: book.Description,
+			})
+			if err != nil {
+				return err
+			}
+			book.WorkID = int64(workId)
+		}
+
+		bookId, err = tx.Book.Insert(book)
 		if err != nil {
-			app.serverErrorResponse(w, r, err)
-			return
+			return err
 		}
-	}
+		book.ID = int64(bookId)
 
-	bookCategories := []data.BookCategory{}
-	for _, categoryId := range input.Categories {
+		if book.Status > 0 {
+			err = tx.Book.SetStatus(user.ID, book)
+			if err != nil {
+				return err
+			}
+		}
 
-		bc := []data.BookCategory{
-			{
-				BookId:     int64(bookId),
-				CategoryId: int64(categoryId),
-			},
+		_, err = tx.Author.InsertWorkAuthors(workAuthorLinks(book.WorkID, input.Authors))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Category.InsertWorkCategories(workCategoryLinks(book.WorkID, input.Categories))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Publisher.InsertBookPublishers(bookPublisherLinks(int64(bookId), input.Publishers))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Series.InsertBookSeries(int64(bookId), input.Series)
+		if err != nil {
+			return err
+		}
+
+		// unknown tags are created on first use
+		tagIds, err := tx.Tag.EnsureTags(book.LibraryID, book.Tags)
+		if err != nil {
+			return err
 		}
-		bookCategories = append(bookCategories, bc...)
-	}
 
-	_, err = app.models.Category.InsertBookCategories(bookCategories)
+		return tx.Tag.InsertBookTags(book.ID, tagIds)
+	})
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrUnknownPublisher):
+			v.AddError("publishers", "must contain existing publisher ids")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
+
 	bookResult := fmt.Sprintf("%q has been aded to your collection!", book.Title)
 
 	jsonResponse := map[string]any{
@@ -118,7 +204,8 @@ func (app *application) createBookHandler(w http.ResponseWriter, r *http.Request
 
 }
 
-// showBookHandler get book by id
+// showBookHandler get book by id, also as a BibTeX, RIS or CSL-JSON citation
+// asked for with cite= or the Accept header
 func (app *application) getBookHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -126,9 +213,22 @@ func (app *application) getBookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	book, err := app.models.Book.GetBook(id)
+	v := validator.New()
+
+	format := app.citationFormat(w, r, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	book, err := app.models.Book.GetBook(id, app.contextGetUser(r))
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -148,18 +248,134 @@ func (app *application) getBookHandler(w http.ResponseWriter, r *http.Request) {
 
 	book.BookAuthors = authors
 
+	publishers, err := app.models.Publisher.GetBookPublishers(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	book.BookPublishers = publishers
+
+	if format != "" {
+		app.writeCitations(w, r, format, []*data.Book{book})
+		return
+	}
+
+	series, err := app.models.Series.GetSeriesForBooks([]int64{id})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	book.BookSeries = series[id]
+
+	tags, err := app.models.Tag.GetTagsForBooks([]int64{id})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	book.Tags = tags[id]
+
+	shelves, err := app.models.Shelf.GetShelvesForBook(id, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	book.Shelves = shelves
+
+	editions, err := app.models.Book.GetWorkEditions(book.WorkID, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// sibling editions only, the book itself is already the response
+	for _, edition := range editions {
+		if edition.ID != book.ID {
+			book.Editions = append(book.Editions, edition)
+		}
+	}
+
 	err = app.writeToJSON(w, http.StatusOK, envelope{"book": book}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
-// getBooksHandler get all books
+// getBooksHandler get all books, also as BibTeX, RIS or CSL-JSON citations asked
+// for with cite= or the Accept header
 func (app *application) getBooksHandler(w http.ResponseWriter, r *http.Request) {
 
 	qs := r.URL.Query()
 
-	books, err := app.models.Book.GetBooks(qs)
+	v := validator.New()
+
+	format := app.citationFormat(w, r, v)
+
+	filters := data.Filters{Sort: app.readStrings(qs, "sort", "title"), SortSafelist: bookSortSafelist}
+	v.Check(validator.PermittedValue(filters.Sort, filters.SortSafelist...), "sort", "invalid sort value")
+
+	v.Check(validator.PermittedValue(qs.Get("tags_match"), "", "any", "all"), "tags_match", "must be any or all")
+
+	for _, ownership := range app.readCSV(qs, "ownership", nil) {
+		v.Check(validator.PermittedValue(ownership, data.Ownerships...), "ownership", "must be a list of wishlist, owned, sold or given_away")
+	}
+
+	for _, format := range app.readCSV(qs, "format", nil) {
+		v.Check(validator.PermittedValue(format, data.BookFormats...), "format", "must be a list of hardcover, paperback, ebook or audiobook")
+	}
+
+	for _, key := range []string{"purchased_from", "purchased_to"} {
+		if qs.Get(key) != "" {
+			_, err := time.Parse("2006-01-02", qs.Get(key))
+			v.Check(err == nil, key, "must be a date in the format YYYY-MM-DD")
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	books, err := app.models.Book.GetBooks(qs, filters, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	bookIds := make([]int64, len(books))
+	for i, b := range books {
+		bookIds[i] = b.ID
+	}
+
+	// load the links for the whole page at once rather than once per book
+	categories, err := app.models.Category.GetCategoriesForBooks(bookIds)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	authors, err := app.models.Author.GetAuthorsForBooks(bookIds)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	publishers, err := app.models.Publisher.GetPublishersForBooks(bookIds)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	series, err := app.models.Series.GetSeriesForBooks(bookIds)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	tags, err := app.models.Tag.GetTagsForBooks(bookIds)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -171,28 +387,31 @@ func (app *application) getBooksHandler(w http.ResponseWriter, r *http.Request)
 		b.DateAdded = b.CreatedAt.UTC().Format(dateLayout)
 		b.DateUpdated = b.UpdatedAt.UTC().Format(dateLayout)
 
-		category, err := app.models.Category.GetBookCategories(b.ID)
-
-		if err != nil {
-			log.Fatal(err)
-			return
-		}
-		for _, cat := range category {
+		for _, cat := range categories[b.ID] {
 			b.Categories = append(b.Categories, int(cat.ID))
 		}
-		b.BookCategories = append(b.BookCategories, category...) // append book categories
+		b.BookCategories = append(b.BookCategories, categories[b.ID]...) // append book categories
 
-		bookAuthors, err := app.models.Author.GetBookAuthors(b.ID)
-		if err != nil {
-			log.Fatal(err)
-			return
+		for _, aut := range authors[b.ID] {
+			if aut.Role == data.RoleAuthor {
+				b.Authors = append(b.Authors, int(aut.AuthorID))
+			}
 		}
+		b.BookAuthors = append(b.BookAuthors, authors[b.ID]...) // append authors
 
-		for _, aut := range bookAuthors {
-			b.Authors = append(b.Authors, int(aut.AuthorID))
+		for _, pub := range publishers[b.ID] {
+			b.Publishers = append(b.Publishers, int(pub.ID))
 		}
-		b.BookAuthors = append(b.BookAuthors, bookAuthors...) // append authors
+		b.BookPublishers = append(b.BookPublishers, publishers[b.ID]...) // append publishers
+
+		b.BookSeries = append(b.BookSeries, series[b.ID]...) // append series
 
+		b.Tags = tags[b.ID]
+	}
+
+	if format != "" {
+		app.writeCitations(w, r, format, books)
+		return
 	}
 
 	err = app.writeToJSON(w, http.StatusOK, envelope{"results": books}, nil)
@@ -212,10 +431,20 @@ func (app *application) deleteBookHandler(w http.ResponseWriter, r *http.Request
 		app.badRequestResponse(w, r, err)
 		return
 	}
-	for _, id := range input.ID {
-		err = app.models.Book.DeleteBook(int64(id))
-	}
+	user := app.contextGetUser(r)
 
+	// either every book is deleted or none of them are
+	err = app.models.RunInTx(func(tx data.Models) error {
+		for _, id := range input.ID {
+			err := tx.Book.DeleteBook(int64(id), user.LibraryID)
+			if err != nil {
+				return err
+			}
+		}
+
+		// works whose last edition was just deleted go with it
+		return tx.Work.DeleteUnusedWorks(user.LibraryID)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -240,7 +469,9 @@ func (app *application) updateBookHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	book, err := app.models.Book.GetBook(id)
+	user := app.contextGetUser(r)
+
+	book, err := app.models.Book.GetBook(id, user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -252,11 +483,21 @@ func (app *application) updateBookHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	var input struct {
-		Id         int     `json:"id"`
-		Title      *string `json:"title"`
-		Status     *int    `json:"status"`
-		Categories []int   `json:"updated_categories"`
-		Authors    []int   `json:"updated_authors"`
+		Id           int                `json:"id"`
+		WorkID       *int64             `json:"work_id"`
+		Title        *string            `json:"title"`
+		Status       *int               `json:"status"`
+		Categories   []int              `json:"updated_categories"`
+		Authors      []data.Contributor `json:"updated_authors"`
+		Publishers   []int              `json:"updated_publishers"`
+		Series       []data.SeriesEntry `json:"updated_series"`
+		Tags         []string           `json:"updated_tags"`
+		Ownership    *string            `json:"ownership"`
+		Format       *string            `json:"format"`
+		PurchaseDate *string            `json:"purchase_date"`
+		Price        *float64           `json:"price"`
+		Currency     *string            `json:"currency"`
+		Vendor       *string            `json:"vendor"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -273,64 +514,177 @@ func (app *application) updateBookHandler(w http.ResponseWriter, r *http.Request
 		book.Status = *input.Status
 	}
 
-	// update categories
-	if input.Categories != nil {
-		err = app.models.Category.DeleteBookCategories(id)
+	if input.Ownership != nil {
+		book.Ownership = *input.Ownership
+	}
+
+	if input.Format != nil {
+		book.Format = *input.Format
+	}
+
+	if input.PurchaseDate != nil {
+		book.PurchaseDate = *input.PurchaseDate
+	}
+
+	if input.Price != nil {
+		book.Price = input.Price
+	}
+
+	if input.Currency != nil {
+		book.Currency = strings.ToUpper(*input.Currency)
+	}
+
+	if input.Vendor != nil {
+		book.Vendor = *input.Vendor
+	}
+
+	v := validator.New()
+
+	if input.Status != nil {
+		data.ValidateStatus(v, book.Status)
+	}
+
+	tags := data.NormalizeTags(input.Tags)
+
+	data.ValidateContributors(v, input.Authors)
+	data.ValidateSeriesEntries(v, input.Series)
+	data.ValidateTags(v, tags)
+
+	err = app.checkSeriesInLibrary(v, input.Series, user.LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// moving the edition to another work; the old work is removed once it has no
+	// editions left
+	if input.WorkID != nil && *input.WorkID != book.WorkID {
+		_, err = app.models.Work.GetWork(*input.WorkID, user.LibraryID)
 		if err != nil {
-			app.serverErrorResponse(w, r, err)
-			return
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("work_id", "must be a work in your library")
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
 		}
+		book.WorkID = *input.WorkID
+	}
+
+	if data.ValidateBook(v, book); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
-		bookCategories := []data.BookCategory{}
-		for _, categoryId := range input.Categories {
+	// links are replaced and the book row updated in one transaction, so either
+	// all of the changes are saved or the book is left as it was
+	err = app.models.RunInTx(func(tx data.Models) error {
+		// authors and categories are replaced on the work, so the change applies
+		// to every edition of it
+		if input.Categories != nil {
+			err := tx.Category.DeleteWorkCategories(book.WorkID)
+			if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+				return err
+			}
 
-			bc := []data.BookCategory{
-				{
-					BookId:     int64(id),
-					CategoryId: int64(categoryId),
-				},
+			_, err = tx.Category.InsertWorkCategories(workCategoryLinks(book.WorkID, input.Categories))
+			if err != nil {
+				return err
 			}
-			bookCategories = append(bookCategories, bc...)
 		}
 
-		_, err = app.models.Category.InsertBookCategories(bookCategories)
-		if err != nil {
-			app.serverErrorResponse(w, r, err)
-			return
+		// the authors and the other contributors are replaced together
+		if input.Authors != nil {
+			err := tx.Author.DeleteWorkAuthors(book.WorkID)
+			if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+				return err
+			}
+
+			_, err = tx.Author.InsertWorkAuthors(workAuthorLinks(book.WorkID, input.Authors))
+			if err != nil {
+				return err
+			}
 		}
 
-	}
+		if input.Publishers != nil {
+			err := tx.Publisher.DeleteBookPublishers(id)
+			if err != nil {
+				return err
+			}
 
-	// update authors
-	if input.Authors != nil {
-		err = app.models.Author.DeleteBookAuthors(id)
-		if err != nil {
-			app.serverErrorResponse(w, r, err)
-			return
+			_, err = tx.Publisher.InsertBookPublishers(bookPublisherLinks(id, input.Publishers))
+			if err != nil {
+				return err
+			}
+		}
+
+		if input.Series != nil {
+			err := tx.Series.DeleteBookSeries(id)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Series.InsertBookSeries(id, input.Series)
+			if err != nil {
+				return err
+			}
 		}
 
-		bookAuthors := []data.BookAuthor{}
-		for _, authorId := range input.Authors {
+		if input.Tags != nil {
+			err := tx.Tag.DeleteBookTags(id)
+			if err != nil {
+				return err
+			}
+
+			tagIds, err := tx.Tag.EnsureTags(user.LibraryID, tags)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Tag.InsertBookTags(id, tagIds)
+			if err != nil {
+				return err
+			}
+		}
 
-			ba := []data.BookAuthor{
-				{
-					BookId:   int64(id),
-					AuthorId: int64(authorId),
-				},
+		if input.Status != nil {
+			err := tx.Book.SetStatus(user.ID, book)
+			if err != nil {
+				return err
 			}
-			bookAuthors = append(bookAuthors, ba...)
 		}
 
-		_, err = app.models.Author.InsertBookAuthors(bookAuthors)
+		err := tx.Book.UpdateBook(book)
 		if err != nil {
-			app.serverErrorResponse(w, r, err)
-			return
+			return err
 		}
 
-	}
-	err = app.models.Book.UpdateBook(book)
+		// the title is the work's, so renaming an edition renames the work
+		if input.Title != nil {
+			work, err := tx.Work.GetWork(book.WorkID, user.LibraryID)
+			if err != nil {
+				return err
+			}
+
+			work.Title = book.Title
+
+			err = tx.Work.UpdateWork(work)
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Work.DeleteUnusedWorks(user.LibraryID)
+	})
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrUnknownPublisher):
+			v.AddError("publishers", "must contain existing publisher ids")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -361,25 +715,101 @@ func (app *application) listBooksHandler(w http.ResponseWriter, r *http.Request)
 	input.Title = app.readStrings(qs, "title", "")
 	input.Authors = app.readCSV(qs, "authors", []string{})
 	input.Categories = app.readCSV(qs, "categories", []string{})
+	input.Status = app.readInt(qs, "status", 0, v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readStrings(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "title", "-id", "-title"}
+	input.Filters.SortSafelist = bookSortSafelist
+
+	v.Check(input.Status >= 0 && input.Status <= 3, "status", "must be between 0 and 3")
+	v.Check(validator.Unique(input.Authors), "authors", "must not contain duplicate values")
+	v.Check(validator.Unique(input.Categories), "categories", "must not contain duplicate values")
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	books, err := app.models.Book.GetFilteredBooks(input.Title, input.Authors, input.Categories, input.Filters)
+	books, metadata, err := app.models.Book.GetFilteredBooks(input.Title, input.Authors, input.Categories, input.Status, input.Filters, app.contextGetUser(r))
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.writeToJSON(w, http.StatusOK, envelope{"books": books}, nil)
+
+	dateLayout := "02/01/2006"
+	for _, b := range books {
+		b.DateAdded = b.CreatedAt.UTC().Format(dateLayout)
+		b.DateUpdated = b.UpdatedAt.UTC().Format(dateLayout)
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"books": books, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 
 }
+
+// workAuthorLinks builds the cg_work_authors rows for a work
+func workAuthorLinks(workId int64, contributors []data.Contributor) []data.WorkAuthor {
+	workAuthors := []data.WorkAuthor{}
+	for _, c := range contributors {
+		workAuthors = append(workAuthors, data.WorkAuthor{
+			WorkId:   workId,
+			AuthorId: c.ID,
+			Role:     c.Role,
+		})
+	}
+
+	return workAuthors
+}
+
+// workCategoryLinks builds the cg_work_categories rows for a work
+func workCategoryLinks(workId int64, categoryIds []int) []data.WorkCategory {
+	workCategories := []data.WorkCategory{}
+	for _, categoryId := range categoryIds {
+		workCategories = append(workCategories, data.WorkCategory{
+			WorkId:     workId,
+			CategoryId: int64(categoryId),
+		})
+	}
+
+	return workCategories
+}
+
+// bookPublisherLinks builds the cg_book_publisher rows for a book
+func bookPublisherLinks(bookId int64, publisherIds []int) []data.BookPublisher {
+	bookPublishers := []data.BookPublisher{}
+	for _, publisherId := range publisherIds {
+		bookPublishers = append(bookPublishers, data.BookPublisher{
+			BookId:      bookId,
+			PublisherId: int64(publisherId),
+		})
+	}
+
+	return bookPublishers
+}
+
+// readBook loads the book named by the :id route parameter from the user's
+// library. It writes the error response itself and returns false when the book
+// can't be loaded, so nested routes like /v1/books/:id/reviews can return early.
+func (app *application) readBook(w http.ResponseWriter, r *http.Request) (*data.Book, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	book, err := app.models.Book.GetBook(id, app.contextGetUser(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return book, true
+}
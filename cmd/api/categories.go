@@ -13,7 +13,8 @@ import (
 // createCategoryHandler creates new category
 func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name string `json:"name"`
+		Name     string `json:"name"`
+		ParentID *int64 `json:"parent_id"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -22,7 +23,21 @@ func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 	category := &data.Category{
-		Name: input.Name,
+		Name:     input.Name,
+		ParentID: input.ParentID,
+	}
+
+	ancestry, err := app.parentAncestry(category)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateCategory(v, category, ancestry); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
 	}
 
 	categoryId, err := app.models.Category.Insert(category)
@@ -63,13 +78,11 @@ func (app *application) getCategoriesHandler(w http.ResponseWriter, r *http.Requ
 		app.notFoundResponse(w, r)
 		return
 	}
-	dateLayout := "02/01/2006"
-	for _, cat := range categories {
-		cat.DateAdded = cat.CreatedAt.UTC().Format(dateLayout)
-		cat.DateUpdated = cat.UpdatedAt.UTC().Format(dateLayout)
-		bookCategories, _ := app.models.Category.GetBooksInCategory(cat.ID)
 
-		cat.BooksInCategory = bookCategories
+	err = app.countBooksInCategories(categories, app.contextGetUser(r).LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	sort.Slice(categories, func(i, j int) bool {
@@ -94,6 +107,10 @@ func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if !app.checkLibraryOnly(w, r, app.models.Category.UsedOutsideLibrary, toInt64s(input.ID)) {
+		return
+	}
+
 	for _, id := range input.ID {
 		err = app.models.Category.DeleteCategory(int64(id))
 	}
@@ -122,6 +139,23 @@ func (app *application) getCategoryHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	category, err := app.models.Category.GetCategory(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	category.BooksInCategory, err = app.models.Category.GetBooksInCategory(id, app.contextGetUser(r).LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	category.BooksInCategoryTotal, err = app.models.Category.GetBooksInCategoryTree(id, app.contextGetUser(r).LibraryID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -152,8 +186,9 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	var input struct {
-		Id   int     `json:"id"`
-		Name *string `json:"name"`
+		Id       int     `json:"id"`
+		Name     *string `json:"name"`
+		ParentID *int64  `json:"parent_id"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -166,13 +201,31 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 		category.Name = *input.Name
 	}
 
+	// a parent_id of 0 moves the category to the top level
+	if input.ParentID != nil {
+		category.ParentID = input.ParentID
+		if *input.ParentID == 0 {
+			category.ParentID = nil
+		}
+	}
+
+	ancestry, err := app.parentAncestry(category)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	v := validator.New()
 
-	if data.ValidateCategory(v, category); !v.Valid() {
+	if data.ValidateCategory(v, category, ancestry); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	if !app.checkLibraryOnly(w, r, app.models.Category.UsedOutsideLibrary, []int64{category.ID}) {
+		return
+	}
+
 	err = app.models.Category.UpdateCategory(category)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -180,7 +233,7 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	jsonResponse := map[string]any{
-		"client_message": "category name has been updated",
+		"client_message": "category has been updated",
 	}
 
 	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
@@ -188,3 +241,58 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// getCategoryTreeHandler get all categories nested under their parents
+func (app *application) getCategoryTreeHandler(w http.ResponseWriter, r *http.Request) {
+
+	categories, err := app.models.Category.GetCategories()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.countBooksInCategories(categories, app.contextGetUser(r).LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": data.CategoryTree(categories)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// countBooksInCategories sets the direct and rolled-up counts of the library's
+// books and the formatted dates of the categories
+func (app *application) countBooksInCategories(categories []*data.Category, libraryID int64) error {
+	booksInCategories, err := app.models.Category.GetBooksInCategories(libraryID)
+	if err != nil {
+		return err
+	}
+
+	booksInCategoryTrees, err := app.models.Category.GetBooksInCategoryTrees(libraryID)
+	if err != nil {
+		return err
+	}
+
+	dateLayout := "02/01/2006"
+	for _, cat := range categories {
+		cat.DateAdded = cat.CreatedAt.UTC().Format(dateLayout)
+		cat.DateUpdated = cat.UpdatedAt.UTC().Format(dateLayout)
+
+		cat.BooksInCategory = booksInCategories[cat.ID]
+		cat.BooksInCategoryTotal = booksInCategoryTrees[cat.ID]
+	}
+
+	return nil
+}
+
+// parentAncestry loads the ancestry of the category's parent for ValidateCategory
+func (app *application) parentAncestry(category *data.Category) ([]int64, error) {
+	if category.ParentID == nil {
+		return nil, nil
+	}
+
+	return app.models.Category.GetCategoryAncestry(*category.ParentID)
+}
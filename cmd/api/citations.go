@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tklara86/book_catalogue/internal/citation"
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// citationFormat picks the citation format the books are wanted in, from the cite
+// query parameter or, without one, the Accept header. It returns "" for the usual
+// JSON response. Every response of the handlers calling it depends on Accept.
+func (app *application) citationFormat(w http.ResponseWriter, r *http.Request, v *validator.Validator) string {
+	w.Header().Add("Vary", "Accept")
+
+	if format := r.URL.Query().Get("cite"); format != "" {
+		v.Check(validator.PermittedValue(format, "json", citation.BibTeX, citation.RIS, citation.CSLJSON), "cite", "must be one of json, bibtex, ris or csl-json")
+		if format == "json" {
+			return ""
+		}
+		return format
+	}
+
+	type accepted struct {
+		mediaType string
+		q         float64
+	}
+
+	accepts := []accepted{}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(s, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if q > 0 {
+			accepts = append(accepts, accepted{mediaType: mediaType, q: q})
+		}
+	}
+
+	// the most preferred type wins, ties go to the type listed first
+	sort.SliceStable(accepts, func(i, j int) bool {
+		return accepts[i].q > accepts[j].q
+	})
+
+	for _, a := range accepts {
+		switch a.mediaType {
+		case "application/json", "application/*", "*/*":
+			return ""
+		}
+
+		for format, mediaType := range citation.MediaTypes {
+			if a.mediaType == mediaType {
+				return format
+			}
+		}
+	}
+
+	return ""
+}
+
+// writeCitations writes the books in the citation format. They need their authors
+// and publishers loaded.
+func (app *application) writeCitations(w http.ResponseWriter, r *http.Request, format string, books []*data.Book) {
+	var buf bytes.Buffer
+
+	err := citation.Write(&buf, format, books)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", citation.MediaTypes[format]+"; charset=utf-8")
+
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -62,3 +62,67 @@ func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http
 	message := "rate limit exceeded"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
+
+func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, errorMessage{
+		Message: "unable to update the record due to an edit conflict, please try again",
+		Status:  http.StatusConflict,
+	})
+}
+
+// usedByOtherLibrariesResponse refuses a change to an author, category or publisher
+// that other libraries link their books to.
+func (app *application) usedByOtherLibrariesResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, errorMessage{
+		Message: "the record is used by books in other libraries and can't be changed",
+		Status:  http.StatusConflict,
+	})
+}
+
+func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusUnauthorized, errorMessage{
+		Message: "invalid authentication credentials",
+		Status:  http.StatusUnauthorized,
+	})
+}
+
+func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	app.errorResponse(w, r, http.StatusUnauthorized, errorMessage{
+		Message: "invalid or missing authentication token",
+		Status:  http.StatusUnauthorized,
+	})
+}
+
+// invalidBasicCredentialsResponse asks for Basic credentials, which clients such as
+// e-reader apps prompt for.
+func (app *application) invalidBasicCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="book_catalogue", charset="UTF-8"`)
+
+	app.errorResponse(w, r, http.StatusUnauthorized, errorMessage{
+		Message: "invalid or missing authentication credentials",
+		Status:  http.StatusUnauthorized,
+	})
+}
+
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusUnauthorized, errorMessage{
+		Message: "you must be authenticated to access this resource",
+		Status:  http.StatusUnauthorized,
+	})
+}
+
+func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusForbidden, errorMessage{
+		Message: "your user account must be activated to access this resource",
+		Status:  http.StatusForbidden,
+	})
+}
+
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusForbidden, errorMessage{
+		Message: "your user account doesn't have the necessary permissions to access this resource",
+		Status:  http.StatusForbidden,
+	})
+}
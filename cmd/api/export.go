@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+)
+
+// bookCSVHeader are the columns of the CSV export, which the CSV import reads back.
+// Lists of names are joined with "; ", authors with another role than author carry
+// it in brackets: "Jane Doe (translator)".
+var bookCSVHeader = []string{
+	"id", "title", "subtitle", "authors", "categories", "publishers", "tags", "isbn",
+	"page_count", "published_date", "description", "image", "status", "ownership",
+	"format", "purchase_date", "price", "currency", "vendor", "date_added",
+}
+
+// csvListSeparator separates the names in the list columns.
+const csvListSeparator = "; "
+
+// exportPageSize is how many books are loaded and written at a time while
+// streaming the export.
+const exportPageSize = 500
+
+// exportBooksCSVHandler streams every book in the library as CSV, a page of books
+// at a time, with the authors, categories, publishers, tags and the user's status
+// flattened into columns
+func (app *application) exportBooksCSVHandler(w http.ResponseWriter, r *http.Request) {
+	cw := csv.NewWriter(w)
+
+	app.streamBooks(w, r, "text/csv; charset=utf-8", "books.csv", bookStream{
+		start: func() error {
+			return cw.Write(bookCSVHeader)
+		},
+		page: func(books []*data.Book) error {
+			err := writeBooksCSV(cw, books)
+			if err != nil {
+				return err
+			}
+			cw.Flush()
+			return cw.Error()
+		},
+	})
+}
+
+// bookStream writes an export: start before the first page of books, page for
+// every page and end after the last one. Any of them can be nil.
+type bookStream struct {
+	start func() error
+	page  func(books []*data.Book) error
+	end   func() error
+}
+
+// streamBooks streams every book in the library as an attachment, loading a page
+// of books with their links at a time and handing it to the stream.
+func (app *application) streamBooks(w http.ResponseWriter, r *http.Request, contentType, filename string, stream bookStream) {
+	user := app.contextGetUser(r)
+
+	filters := data.Filters{Page: 1, PageSize: exportPageSize, Sort: "id", SortSafelist: []string{"id"}}
+
+	books, metadata, err := app.models.Book.GetFilteredBooks("", nil, nil, 0, filters, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// once the first bytes are out the status can't change any more, later errors
+	// are only logged and cut the export short
+	if stream.start != nil {
+		err = stream.start()
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+	}
+
+	for {
+		err = app.loadBookLinks(books)
+		if err == nil && stream.page != nil {
+			err = stream.page(books)
+		}
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		if filters.Page >= metadata.LastPage {
+			break
+		}
+
+		filters.Page++
+
+		books, _, err = app.models.Book.GetFilteredBooks("", nil, nil, 0, filters, user)
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+	}
+
+	if stream.end != nil {
+		err = stream.end()
+		if err != nil {
+			app.logError(r, err)
+		}
+	}
+}
+
+// loadBookLinks loads the authors, categories, publishers and tags of a page of
+// books in one go.
+func (app *application) loadBookLinks(books []*data.Book) error {
+	bookIds := make([]int64, len(books))
+	for i, b := range books {
+		bookIds[i] = b.ID
+	}
+
+	authors, err := app.models.Author.GetAuthorsForBooks(bookIds)
+	if err != nil {
+		return err
+	}
+
+	categories, err := app.models.Category.GetCategoriesForBooks(bookIds)
+	if err != nil {
+		return err
+	}
+
+	publishers, err := app.models.Publisher.GetPublishersForBooks(bookIds)
+	if err != nil {
+		return err
+	}
+
+	tags, err := app.models.Tag.GetTagsForBooks(bookIds)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range books {
+		b.BookAuthors = authors[b.ID]
+		b.BookCategories = categories[b.ID]
+		b.BookPublishers = publishers[b.ID]
+		b.Tags = tags[b.ID]
+	}
+
+	return nil
+}
+
+// writeBooksCSV writes a page of books with their links loaded.
+func writeBooksCSV(cw *csv.Writer, books []*data.Book) error {
+	dateLayout := "02/01/2006"
+
+	for _, b := range books {
+		authorNames := []string{}
+		for _, a := range b.BookAuthors {
+			name := strings.TrimSpace(a.FirstName + " " + a.LastName)
+			if a.Role != data.RoleAuthor {
+				name = fmt.Sprintf("%s (%s)", name, a.Role)
+			}
+			authorNames = append(authorNames, name)
+		}
+
+		categoryNames := []string{}
+		for _, c := range b.BookCategories {
+			categoryNames = append(categoryNames, c.Name)
+		}
+
+		publisherNames := []string{}
+		for _, p := range b.BookPublishers {
+			publisherNames = append(publisherNames, p.Name)
+		}
+
+		pageCount := ""
+		if b.PageCount > 0 {
+			pageCount = strconv.Itoa(b.PageCount)
+		}
+
+		price := ""
+		if b.Price != nil {
+			price = strconv.FormatFloat(*b.Price, 'f', 2, 64)
+		}
+
+		err := cw.Write([]string{
+			strconv.FormatInt(b.ID, 10),
+			b.Title,
+			b.Subtitle,
+			strings.Join(authorNames, csvListSeparator),
+			strings.Join(categoryNames, csvListSeparator),
+			strings.Join(publisherNames, csvListSeparator),
+			strings.Join(b.Tags, csvListSeparator),
+			b.ISBN,
+			pageCount,
+			b.PublishedDate,
+			b.Description,
+			b.Image,
+			b.StatusName,
+			b.Ownership,
+			b.Format,
+			b.PurchaseDate,
+			price,
+			b.Currency,
+			b.Vendor,
+			b.CreatedAt.UTC().Format(dateLayout),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
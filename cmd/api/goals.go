@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// getGoalsHandler get the current user's reading goals
+func (app *application) getGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	goals, err := app.models.Goal.GetGoals(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	for _, goal := range goals {
+		goal.DateAdded = goal.CreatedAt.UTC().Format(dateLayout)
+		goal.DateUpdated = goal.UpdatedAt.UTC().Format(dateLayout)
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": goals}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// setGoalHandler sets the current user's reading goal for the year in the route,
+// replacing the one already set
+func (app *application) setGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, err := app.readNamedIDParam(r, "year")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Target int    `json:"target"`
+		Unit   string `json:"unit"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	goal := &data.ReadingGoal{
+		UserID: app.contextGetUser(r).ID,
+		Year:   int(year),
+		Target: input.Target,
+		Unit:   input.Unit,
+	}
+
+	if goal.Unit == "" {
+		goal.Unit = data.GoalUnitBooks
+	}
+
+	v := validator.New()
+
+	if data.ValidateReadingGoal(v, goal); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Goal.SetGoal(goal)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"client_message": "your reading goal has been set",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getGoalHandler get the current user's progress towards the year's goal, with
+// the pace and a per-month breakdown
+func (app *application) getGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, err := app.readNamedIDParam(r, "year")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	goal, err := app.models.Goal.GetGoal(user.ID, int(year))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	months, err := app.models.Goal.GetMonthlyFinished(user.ID, user.LibraryID, goal.Year)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"goal": goal.Progress(months, time.Now().UTC())}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteGoalHandler removes the current user's goal for the year
+func (app *application) deleteGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, err := app.readNamedIDParam(r, "year")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Goal.DeleteGoal(app.contextGetUser(r).ID, int(year))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"message": "reading goal successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -17,11 +17,17 @@ import (
 type envelope map[string]any
 
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readNamedIDParam(r, "id")
+}
+
+// readNamedIDParam reads a positive id from a named route parameter, for routes
+// with more than one id such as /v1/books/:id/reviews/:review_id.
+func (app *application) readNamedIDParam(r *http.Request, name string) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 
 	return id, nil
@@ -164,3 +170,46 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	// Otherwise, return the converted integer value.
 	return i
 }
+
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be true or false")
+		return defaultValue
+	}
+
+	return b
+}
+
+// checkLibraryOnly reports whether records shared by every library, the authors,
+// categories and publishers in ids, may be changed by the current user: none of
+// them may be linked to books in another library. Otherwise it writes the error
+// response itself.
+func (app *application) checkLibraryOnly(w http.ResponseWriter, r *http.Request, usedOutsideLibrary func([]int64, int64) (bool, error), ids []int64) bool {
+	used, err := usedOutsideLibrary(ids, app.contextGetUser(r).LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if used {
+		app.usedByOtherLibrariesResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+func toInt64s(ids []int) []int64 {
+	int64s := make([]int64, len(ids))
+	for i, id := range ids {
+		int64s[i] = int64(id)
+	}
+	return int64s
+}
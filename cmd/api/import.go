@@ -0,0 +1,663 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+const (
+	maxImportBytes = 10 << 20
+	maxImportRows  = 5000
+)
+
+// Outcomes of importing a row.
+const (
+	importCreated     = "created"
+	importWouldCreate = "would_create"
+	importInvalid     = "invalid"
+	importSkipped     = "skipped"
+)
+
+// bookImport is a row of an import file read into a book. Authors, categories and
+// publishers are names, matched against the catalogue or created when the row is
+// imported. ISBNs are other ISBNs of the edition, also checked for duplicates.
+// Reading history exported from other sites carries the user's rating, review and
+// when they finished the book (YYYY-MM-DD). Errors holds the values of the row
+// that couldn't be read.
+type bookImport struct {
+	Row        int
+	Book       *data.Book
+	Authors    []string
+	Categories []string
+	Publishers []string
+	ISBNs      []string
+	Rating     float64
+	Review     string
+	Spoiler    bool
+	DateRead   string
+	Errors     map[string]string
+}
+
+type importResult struct {
+	Row           int               `json:"row"`
+	Title         string            `json:"title"`
+	Status        string            `json:"status"`
+	BookID        int64             `json:"book_id,omitempty"`
+	Message       string            `json:"message,omitempty"`
+	Errors        map[string]string `json:"errors,omitempty"`
+	NewAuthors    []string          `json:"new_authors,omitempty"`
+	NewCategories []string          `json:"new_categories,omitempty"`
+	NewPublishers []string          `json:"new_publishers,omitempty"`
+}
+
+type importSummary struct {
+	Rows    int `json:"rows"`
+	Created int `json:"created"`
+	Invalid int `json:"invalid"`
+	Skipped int `json:"skipped"`
+}
+
+// importBooksHandler imports the books of a CSV file in the export's format, sent
+// as the "file" field of a multipart form or as the request body. Every row is
+// reported on, with ?dry_run=true nothing is saved
+func (app *application) importBooksHandler(w http.ResponseWriter, r *http.Request) {
+	app.importExportFile(w, r, bookImportsFromCSV)
+}
+
+// writeImportResults imports the rows and writes the report.
+func (app *application) writeImportResults(w http.ResponseWriter, r *http.Request, imports []*bookImport, dryRun bool) {
+	results, summary, err := app.importBooks(imports, app.contextGetUser(r), dryRun)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"dry_run": dryRun, "summary": summary, "results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readImportUpload reads the uploaded file, either the "file" field of a multipart
+// form or the whole request body.
+func (app *application) readImportUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var file io.Reader = r.Body
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		upload, _, err := r.FormFile("file")
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+			}
+			return nil, errors.New("the form must have a file field")
+		}
+		defer upload.Close()
+		file = upload
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// readImportCSV reads the uploaded CSV file, see readImportUpload. Rows may have
+// different lengths.
+func (app *application) readImportCSV(w http.ResponseWriter, r *http.Request) ([][]string, error) {
+	content, err := app.readImportUpload(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(bytes.NewReader(content))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("the file is not valid CSV: %v", err)
+	}
+
+	return records, nil
+}
+
+// csvColumns maps the lower-cased names in the header row to their index, spaces
+// in the names are read as underscores.
+func csvColumns(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+
+	return columns
+}
+
+// csvRow returns a function reading the trimmed value of a named column of the
+// record, empty when the file has no such column.
+func csvRow(columns map[string]int, record []string) func(name string) string {
+	return func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+}
+
+// checkImportFile checks the header and the number of rows of an import file.
+func checkImportFile(v *validator.Validator, records [][]string, required ...string) map[string]int {
+	if len(records) == 0 {
+		v.AddError("file", "must not be empty")
+		return nil
+	}
+
+	columns := csvColumns(records[0])
+
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			v.AddError("file", fmt.Sprintf("must have a %s column", name))
+		}
+	}
+
+	v.Check(len(records)-1 <= maxImportRows, "file", fmt.Sprintf("must not have more than %d rows", maxImportRows))
+
+	return columns
+}
+
+// bookImportsFromCSV reads the rows of a file in the export's format, see
+// bookCSVHeader. Blank rows are left out, the id and date_added columns are
+// ignored.
+func bookImportsFromCSV(records [][]string, v *validator.Validator) []*bookImport {
+	columns := checkImportFile(v, records, "title")
+	if !v.Valid() {
+		return nil
+	}
+
+	imports := []*bookImport{}
+
+	for i, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		value := csvRow(columns, record)
+
+		bi := &bookImport{
+			Row:        i + 2,
+			Authors:    splitCSVList(value("authors")),
+			Categories: splitCSVList(value("categories")),
+			Publishers: splitCSVList(value("publishers")),
+			Errors:     map[string]string{},
+		}
+
+		bi.Book = &data.Book{
+			Title:         value("title"),
+			Subtitle:      value("subtitle"),
+			ISBN:          value("isbn"),
+			PublishedDate: value("published_date"),
+			Description:   value("description"),
+			Image:         value("image"),
+			Ownership:     strings.ToLower(value("ownership")),
+			Format:        strings.ToLower(value("format")),
+			PurchaseDate:  value("purchase_date"),
+			Currency:      strings.ToUpper(value("currency")),
+			Vendor:        value("vendor"),
+			Tags:          data.NormalizeTags(splitCSVList(value("tags"))),
+		}
+
+		bi.Book.PageCount = bi.readInt(value("page_count"), "page_count")
+
+		if s := value("price"); s != "" {
+			price, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				bi.Errors["price"] = "must be a number"
+			} else {
+				bi.Book.Price = &price
+			}
+		}
+
+		status, ok := data.ParseStatus(value("status"))
+		if !ok {
+			bi.Errors["status"] = "must be one of Not Read, In progress or Read"
+		}
+		bi.Book.Status = status
+
+		imports = append(imports, bi)
+	}
+
+	return imports
+}
+
+// readInt reads a whole number that can't be negative, recording an error for the
+// column when it isn't one. Empty values are 0.
+func (bi *bookImport) readInt(s string, column string) int {
+	if s == "" {
+		return 0
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		bi.Errors[column] = "must be a whole number"
+		return 0
+	}
+
+	return n
+}
+
+// splitCSVList splits a list column on semicolons, leaving out empty names.
+func splitCSVList(s string) []string {
+	names := []string{}
+
+	for _, name := range strings.Split(s, ";") {
+		name = strings.Join(strings.Fields(name), " ")
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// parseContributorName splits the role off a name written as "Jane Doe (translator)",
+// names without a role are authors.
+func parseContributorName(name string) (string, string) {
+	if strings.HasSuffix(name, ")") {
+		if i := strings.LastIndex(name, " ("); i > 0 {
+			role := strings.ToLower(name[i+2 : len(name)-1])
+			if validator.PermittedValue(role, data.ContributorRoles...) {
+				return strings.TrimSpace(name[:i]), role
+			}
+		}
+	}
+
+	return name, data.RoleAuthor
+}
+
+// nameCache maps the lower-cased names already resolved during an import to their
+// ids. In a dry run names that would be created are cached with id 0.
+type nameCache map[string]int64
+
+// resolve returns the ids of the names, finding them in the cache or through find
+// and otherwise creating them. Without create nothing is created and only the new
+// names are returned. created lists the names that are new to the import.
+func (c nameCache) resolve(names []string, find func(string) (int64, error), create func(string) (int64, error)) (ids []int64, created []string, err error) {
+	for _, name := range names {
+		key := strings.ToLower(name)
+
+		id, ok := c[key]
+		if !ok {
+			id, err = find(name)
+			switch {
+			case err == nil:
+			case errors.Is(err, data.ErrRecordNotFound):
+				created = append(created, name)
+				if create != nil {
+					id, err = create(name)
+					if err != nil {
+						return nil, nil, err
+					}
+				}
+			default:
+				return nil, nil, err
+			}
+			c[key] = id
+		}
+
+		if id > 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, created, nil
+}
+
+// bookImporter imports the rows of one file, the names resolved so far are shared
+// between the rows.
+type bookImporter struct {
+	models     data.Models
+	user       *data.User
+	dryRun     bool
+	authors    nameCache
+	categories nameCache
+	publishers nameCache
+	isbns      map[string]bool
+}
+
+// importBooks imports the rows one at a time, each in its own transaction, so an
+// invalid row doesn't stop the rest of the file.
+func (app *application) importBooks(imports []*bookImport, user *data.User, dryRun bool) ([]*importResult, importSummary, error) {
+	im := &bookImporter{
+		models:     app.models,
+		user:       user,
+		dryRun:     dryRun,
+		authors:    nameCache{},
+		categories: nameCache{},
+		publishers: nameCache{},
+		isbns:      map[string]bool{},
+	}
+
+	results := make([]*importResult, 0, len(imports))
+	summary := importSummary{Rows: len(imports)}
+
+	for _, bi := range imports {
+		result, err := im.importBook(bi)
+		if err != nil {
+			return nil, importSummary{}, fmt.Errorf("importing row %d: %w", bi.Row, err)
+		}
+
+		switch result.Status {
+		case importCreated, importWouldCreate:
+			summary.Created++
+		case importInvalid:
+			summary.Invalid++
+		case importSkipped:
+			summary.Skipped++
+		}
+
+		results = append(results, result)
+	}
+
+	return results, summary, nil
+}
+
+// contributorName is an author name from an import with the role it was given.
+type contributorName struct {
+	name string
+	role string
+}
+
+func (im *bookImporter) importBook(bi *bookImport) (*importResult, error) {
+	book := bi.Book
+	book.LibraryID = im.user.LibraryID
+
+	if book.Ownership == "" {
+		book.Ownership = data.OwnershipOwned
+	}
+
+	result := &importResult{Row: bi.Row, Title: book.Title}
+
+	v := validator.New()
+
+	for key, message := range bi.Errors {
+		v.AddError(key, message)
+	}
+
+	data.ValidateBook(v, book)
+	data.ValidateTags(v, book.Tags)
+
+	v.Check(len(book.Title) <= 255, "title", "must not be more than 255 bytes long")
+	v.Check(len(book.Subtitle) <= 255, "subtitle", "must not be more than 255 bytes long")
+	v.Check(len(book.ISBN) <= 255, "isbn", "must not be more than 255 bytes long")
+	v.Check(len(book.PublishedDate) <= 25, "published_date", "must not be more than 25 bytes long")
+	v.Check(len(book.Image) <= 1000, "image", "must not be more than 1000 bytes long")
+
+	// the same name twice in a row is only linked once
+	contributors := []contributorName{}
+	seen := map[contributorName]bool{}
+	for _, name := range bi.Authors {
+		name, role := parseContributorName(name)
+		v.Check(len(name) <= 255, "authors", "names must not be more than 255 bytes long")
+
+		// "Last, First" is matched as "First Last"
+		first, last := data.SplitAuthorName(name)
+		c := contributorName{name: strings.TrimSpace(first + " " + last), role: role}
+		key := contributorName{name: strings.ToLower(c.name), role: role}
+		if c.name != "" && !seen[key] {
+			seen[key] = true
+			contributors = append(contributors, c)
+		}
+	}
+
+	categories := uniqueNames(bi.Categories)
+	for _, name := range categories {
+		v.Check(len(name) <= 255, "categories", "names must not be more than 255 bytes long")
+	}
+
+	publishers := uniqueNames(bi.Publishers)
+	for _, name := range publishers {
+		v.Check(len(name) <= 255, "publishers", "names must not be more than 255 bytes long")
+	}
+
+	// a rating becomes the user's review of the book
+	var review *data.Review
+	if bi.Rating > 0 {
+		review = &data.Review{
+			UserID:       im.user.ID,
+			Rating:       bi.Rating,
+			Review:       bi.Review,
+			Spoiler:      bi.Spoiler,
+			DateFinished: bi.DateRead,
+		}
+		data.ValidateReview(v, review)
+	}
+
+	var dateRead time.Time
+	if bi.DateRead != "" {
+		var err error
+		dateRead, err = time.Parse("2006-01-02", bi.DateRead)
+		v.Check(err == nil, "date_read", "must be a date in the format YYYY-MM-DD")
+		v.Check(err != nil || !dateRead.After(time.Now()), "date_read", "must not be in the future")
+	}
+
+	if !v.Valid() {
+		result.Status = importInvalid
+		result.Errors = v.Errors
+		return result, nil
+	}
+
+	// a book already in the library, or earlier in the file, isn't added twice
+	isbns := uniqueNames(append([]string{book.ISBN}, bi.ISBNs...))
+	for _, isbn := range isbns {
+		if isbn == "" {
+			continue
+		}
+
+		exists := im.isbns[isbn]
+		if !exists {
+			var err error
+			exists, err = im.models.Book.ISBNInLibrary(isbn, book.LibraryID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if exists {
+			result.Status = importSkipped
+			result.Message = fmt.Sprintf("a book with the ISBN %s is already in the library", isbn)
+			return result, nil
+		}
+	}
+
+	authorNames := make([]string, len(contributors))
+	for i, c := range contributors {
+		authorNames[i] = c.name
+	}
+
+	if im.dryRun {
+		var err error
+
+		_, result.NewAuthors, err = im.authors.resolve(authorNames, im.models.Author.GetAuthorIDByName, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		_, result.NewCategories, err = im.categories.resolve(categories, im.models.Category.GetCategoryIDByName, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		_, result.NewPublishers, err = im.publishers.resolve(publishers, im.models.Publisher.GetPublisherIDByName, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		im.seenISBNs(isbns)
+
+		result.Status = importWouldCreate
+		return result, nil
+	}
+
+	err := im.models.RunInTx(func(tx data.Models) error {
+		authorIds, newAuthors, err := im.authors.resolve(authorNames, tx.Author.GetAuthorIDByName, func(name string) (int64, error) {
+			first, last := data.SplitAuthorName(name)
+			id, err := tx.Author.Insert(&data.Author{FirstName: first, LastName: last})
+			return int64(id), err
+		})
+		if err != nil {
+			return err
+		}
+
+		categoryIds, newCategories, err := im.categories.resolve(categories, tx.Category.GetCategoryIDByName, func(name string) (int64, error) {
+			id, err := tx.Category.Insert(&data.Category{Name: name})
+			return int64(id), err
+		})
+		if err != nil {
+			return err
+		}
+
+		publisherIds, newPublishers, err := im.publishers.resolve(publishers, tx.Publisher.GetPublisherIDByName, func(name string) (int64, error) {
+			id, err := tx.Publisher.Insert(&data.Publisher{Name: name})
+			return int64(id), err
+		})
+		if err != nil {
+			return err
+		}
+
+		result.NewAuthors, result.NewCategories, result.NewPublishers = newAuthors, newCategories, newPublishers
+
+		workId, err := tx.Work.Insert(&data.Work{
+			LibraryID:   book.LibraryID,
+			Title:       book.Title,
+			Description: book.Description,
+		})
+		if err != nil {
+			return err
+		}
+		book.WorkID = int64(workId)
+
+		bookId, err := tx.Book.Insert(book)
+		if err != nil {
+			return err
+		}
+		book.ID = int64(bookId)
+
+		if book.Status > 0 {
+			err = tx.Book.SetStatus(im.user.ID, book)
+			if err != nil {
+				return err
+			}
+		}
+
+		if book.Status == data.StatusRead && !dateRead.IsZero() {
+			err = tx.Book.SetFinishedAt(im.user.ID, book.ID, dateRead)
+			if err != nil {
+				return err
+			}
+		}
+
+		if review != nil {
+			review.BookID = book.ID
+			_, err = tx.Review.Insert(review)
+			if err != nil {
+				return err
+			}
+		}
+
+		workContributors := make([]data.Contributor, len(contributors))
+		for i, c := range contributors {
+			workContributors[i] = data.Contributor{ID: authorIds[i], Role: c.role}
+		}
+
+		_, err = tx.Author.InsertWorkAuthors(workAuthorLinks(book.WorkID, workContributors))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Category.InsertWorkCategories(workCategoryLinks(book.WorkID, toInts(categoryIds)))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Publisher.InsertBookPublishers(bookPublisherLinks(book.ID, toInts(publisherIds)))
+		if err != nil {
+			return err
+		}
+
+		tagIds, err := tx.Tag.EnsureTags(book.LibraryID, book.Tags)
+		if err != nil {
+			return err
+		}
+
+		return tx.Tag.InsertBookTags(book.ID, tagIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	im.seenISBNs(isbns)
+
+	result.Status = importCreated
+	result.BookID = book.ID
+	return result, nil
+}
+
+// seenISBNs records the ISBNs of an imported row so later rows with them are
+// skipped.
+func (im *bookImporter) seenISBNs(isbns []string) {
+	for _, isbn := range isbns {
+		if isbn != "" {
+			im.isbns[isbn] = true
+		}
+	}
+}
+
+// uniqueNames leaves out the names repeated in the list, ignoring case.
+func uniqueNames(names []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, name)
+		}
+	}
+
+	return unique
+}
+
+func toInts(ids []int64) []int {
+	ints := make([]int, len(ids))
+	for i, id := range ids {
+		ints[i] = int(id)
+	}
+	return ints
+}
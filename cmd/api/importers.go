@@ -0,0 +1,255 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// importGoodreadsHandler imports a Goodreads library export ("Export Library" in
+// the Goodreads settings), see importBooksHandler for the upload and the report
+func (app *application) importGoodreadsHandler(w http.ResponseWriter, r *http.Request) {
+	app.importExportFile(w, r, goodreadsImports)
+}
+
+// importStoryGraphHandler imports a StoryGraph export ("Export StoryGraph Library"
+// in the StoryGraph account settings), see importBooksHandler for the upload and
+// the report
+func (app *application) importStoryGraphHandler(w http.ResponseWriter, r *http.Request) {
+	app.importExportFile(w, r, storyGraphImports)
+}
+
+// importExportFile reads the uploaded file with read, which turns its rows into
+// books, and imports them.
+func (app *application) importExportFile(w http.ResponseWriter, r *http.Request, read func([][]string, *validator.Validator) []*bookImport) {
+	v := validator.New()
+
+	dryRun := app.readBool(r.URL.Query(), "dry_run", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	records, err := app.readImportCSV(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	imports := read(records, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.writeImportResults(w, r, imports, dryRun)
+}
+
+// goodreadsStatuses maps the Goodreads exclusive shelves onto reading statuses.
+var goodreadsStatuses = map[string]int{
+	"read":              data.StatusRead,
+	"currently-reading": data.StatusInProgress,
+	"to-read":           data.StatusNotRead,
+}
+
+// goodreadsImports reads the rows of a Goodreads export. The exclusive shelf gives
+// the status, other shelves become tags. "My Rating" and "My Review" become the
+// user's review and "Date Read" when the book was finished.
+func goodreadsImports(records [][]string, v *validator.Validator) []*bookImport {
+	columns := checkImportFile(v, records, "title", "exclusive_shelf")
+	if !v.Valid() {
+		return nil
+	}
+
+	imports := []*bookImport{}
+
+	for i, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		value := csvRow(columns, record)
+
+		bi := &bookImport{
+			Row:        i + 2,
+			Publishers: splitCSVList(value("publisher")),
+			Review:     value("my_review"),
+			Errors:     map[string]string{},
+		}
+
+		// "Author l-f" is "Last, First", the additional authors are "First Last"
+		author := value("author_l-f")
+		if author == "" {
+			author = value("author")
+		}
+		if author != "" {
+			bi.Authors = append(bi.Authors, author)
+		}
+		bi.Authors = append(bi.Authors, splitCommaList(value("additional_authors"))...)
+
+		isbn13, isbn := goodreadsISBN(value("isbn13")), goodreadsISBN(value("isbn"))
+
+		bi.Book = &data.Book{
+			Title:         value("title"),
+			ISBN:          isbn13,
+			PublishedDate: value("year_published"),
+			Format:        bookFormat(value("binding")),
+		}
+		if bi.Book.ISBN == "" {
+			bi.Book.ISBN = isbn
+		} else if isbn != "" {
+			bi.ISBNs = []string{isbn}
+		}
+
+		bi.Book.PageCount = bi.readInt(value("number_of_pages"), "number_of_pages")
+		bi.Rating = float64(bi.readInt(value("my_rating"), "my_rating"))
+		bi.Spoiler, _ = strconv.ParseBool(value("spoiler"))
+		bi.DateRead = bi.readDate(value("date_read"), "date_read")
+
+		shelf := strings.ToLower(value("exclusive_shelf"))
+		status, ok := goodreadsStatuses[shelf]
+		bi.Book.Status = status
+
+		tags := []string{}
+		if !ok && shelf != "" {
+			// custom exclusive shelves don't have a status, the shelf is kept as a tag
+			tags = append(tags, shelf)
+		}
+		for _, s := range splitCommaList(value("bookshelves")) {
+			if _, ok := goodreadsStatuses[strings.ToLower(s)]; !ok {
+				tags = append(tags, s)
+			}
+		}
+		bi.Book.Tags = data.NormalizeTags(tags)
+
+		imports = append(imports, bi)
+	}
+
+	return imports
+}
+
+// goodreadsISBN strips the spreadsheet quoting Goodreads puts around ISBNs,
+// ="0439023483", leaving "" for books without one.
+func goodreadsISBN(s string) string {
+	return strings.Trim(strings.TrimPrefix(s, "="), `"`)
+}
+
+// storyGraphStatuses maps the StoryGraph read statuses onto reading statuses.
+var storyGraphStatuses = map[string]int{
+	"read":              data.StatusRead,
+	"currently-reading": data.StatusInProgress,
+	"to-read":           data.StatusNotRead,
+	"did-not-finish":    data.StatusNotRead,
+	"paused":            data.StatusInProgress,
+}
+
+// storyGraphImports reads the rows of a StoryGraph export. "Star Rating" and
+// "Review" become the user's review and "Last Date Read" when the book was
+// finished. Books the user didn't finish are tagged did-not-finish.
+func storyGraphImports(records [][]string, v *validator.Validator) []*bookImport {
+	columns := checkImportFile(v, records, "title", "read_status")
+	if !v.Valid() {
+		return nil
+	}
+
+	imports := []*bookImport{}
+
+	for i, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		value := csvRow(columns, record)
+
+		bi := &bookImport{
+			Row:     i + 2,
+			Authors: splitCommaList(value("authors")),
+			Review:  value("review"),
+			Errors:  map[string]string{},
+		}
+
+		// contributors are "Jane Doe (Translator)", roles we don't keep are left out
+		for _, name := range splitCommaList(value("contributors")) {
+			if _, role := parseContributorName(name); role != data.RoleAuthor {
+				bi.Authors = append(bi.Authors, name)
+			}
+		}
+
+		bi.Book = &data.Book{
+			Title:  value("title"),
+			ISBN:   value("isbn/uid"),
+			Format: bookFormat(value("format")),
+		}
+
+		if s := value("star_rating"); s != "" {
+			rating, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				bi.Errors["star_rating"] = "must be a number"
+			}
+			// StoryGraph rates in quarter stars, reviews here in half stars
+			bi.Rating = math.Round(rating*2) / 2
+		}
+
+		bi.DateRead = bi.readDate(value("last_date_read"), "last_date_read")
+
+		readStatus := strings.ToLower(value("read_status"))
+		bi.Book.Status = storyGraphStatuses[readStatus]
+
+		tags := splitCommaList(value("tags"))
+		if readStatus == "did-not-finish" {
+			tags = append(tags, "did-not-finish")
+		}
+		bi.Book.Tags = data.NormalizeTags(tags)
+
+		imports = append(imports, bi)
+	}
+
+	return imports
+}
+
+// readDate reads a date written 2023/05/14, as the Goodreads and StoryGraph
+// exports do, or 2023-05-14, into the YYYY-MM-DD format.
+func (bi *bookImport) readDate(s string, column string) string {
+	if s == "" {
+		return ""
+	}
+
+	for _, layout := range []string{"2006/01/02", "2006-01-02", "2006/1/2"} {
+		date, err := time.Parse(layout, s)
+		if err == nil {
+			return date.Format("2006-01-02")
+		}
+	}
+
+	bi.Errors[column] = "must be a date such as 2023/05/14"
+	return ""
+}
+
+// splitCommaList splits a comma separated list, leaving out empty names.
+func splitCommaList(s string) []string {
+	return splitCSVList(strings.ReplaceAll(s, ",", ";"))
+}
+
+// bookFormat maps the bindings and formats of other sites onto the book formats,
+// unknown ones are left empty.
+func bookFormat(binding string) string {
+	binding = strings.ToLower(binding)
+
+	switch {
+	case strings.Contains(binding, "audio"):
+		return "audiobook"
+	case strings.Contains(binding, "hardcover"), strings.Contains(binding, "hardback"):
+		return "hardcover"
+	case strings.Contains(binding, "paperback"), strings.Contains(binding, "mass market"):
+		return "paperback"
+	case strings.Contains(binding, "kindle"), strings.Contains(binding, "ebook"), strings.Contains(binding, "digital"), strings.Contains(binding, "nook"):
+		return "ebook"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// getLibraryHandler get the library of the current user with its members
+func (app *application) getLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	library, err := app.models.Library.GetLibrary(user.LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	members, err := app.models.User.GetForLibrary(library.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	library.DateAdded = library.CreatedAt.UTC().Format(dateLayout)
+	library.DateUpdated = library.UpdatedAt.UTC().Format(dateLayout)
+	library.Members = members
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"library": library}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateLibraryHandler renames the library of the current user
+func (app *application) updateLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	library, err := app.models.Library.GetLibrary(user.LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		library.Name = *input.Name
+	}
+
+	v := validator.New()
+
+	if data.ValidateLibrary(v, library); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Library.UpdateLibrary(library)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"client_message": "library name has been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateUserLibraryHandler moves a user into another library so a household can
+// share one catalogue
+func (app *application) updateUserLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := app.models.User.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		LibraryID int64 `json:"library_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	library, err := app.models.Library.GetLibrary(input.LibraryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v := validator.New()
+			v.AddError("library_id", "library does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user.LibraryID = library.ID
+
+	err = app.models.User.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
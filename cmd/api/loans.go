@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// getBookLoansHandler get the lending history of a book
+func (app *application) getBookLoansHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	loans, err := app.models.Loan.GetBookLoans(book.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	formatLoanDates(loans)
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"lent_out": book.LentOut, "results": loans}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createLoanHandler records a book being lent out
+func (app *application) createLoanHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		BorrowerName    string `json:"borrower_name"`
+		BorrowerContact string `json:"borrower_contact"`
+		LentAt          string `json:"lent_at"`
+		DueAt           string `json:"due_at"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	loan := &data.Loan{
+		BookID:          book.ID,
+		BorrowerName:    input.BorrowerName,
+		BorrowerContact: input.BorrowerContact,
+		LentAt:          input.LentAt,
+		DueAt:           input.DueAt,
+	}
+
+	// lent today unless told otherwise
+	if loan.LentAt == "" {
+		loan.LentAt = time.Now().UTC().Format("2006-01-02")
+	}
+
+	v := validator.New()
+
+	v.Check(!book.LentOut, "book_id", fmt.Sprintf("is already lent to %s", book.Borrower))
+
+	if data.ValidateLoan(v, loan); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	loanId, err := app.models.Loan.Insert(loan)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrBookLentOut):
+			v.AddError("book_id", "is already lent out")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"loan_id":        loanId,
+		"client_message": fmt.Sprintf("%q has been lent to %s", book.Title, loan.BorrowerName),
+	}
+
+	err = app.writeToJSON(w, http.StatusCreated, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateLoanHandler updates a loan, setting returned_at marks the book as back
+func (app *application) updateLoanHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := app.readNamedIDParam(r, "loan_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	loan, err := app.models.Loan.GetLoan(id, book.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		BorrowerName    *string `json:"borrower_name"`
+		BorrowerContact *string `json:"borrower_contact"`
+		LentAt          *string `json:"lent_at"`
+		DueAt           *string `json:"due_at"`
+		ReturnedAt      *string `json:"returned_at"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.BorrowerName != nil {
+		loan.BorrowerName = *input.BorrowerName
+	}
+
+	if input.BorrowerContact != nil {
+		loan.BorrowerContact = *input.BorrowerContact
+	}
+
+	if input.LentAt != nil {
+		loan.LentAt = *input.LentAt
+	}
+
+	if input.DueAt != nil {
+		loan.DueAt = *input.DueAt
+	}
+
+	if input.ReturnedAt != nil {
+		loan.ReturnedAt = *input.ReturnedAt
+	}
+
+	v := validator.New()
+
+	if data.ValidateLoan(v, loan); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Loan.UpdateLoan(loan)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrBookLentOut):
+			v.AddError("returned_at", "the book has been lent out again since")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"client_message": "loan has been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getLoansHandler lists the books currently lent out of the library, by default
+// only the overdue ones, ?status=active lists every outstanding loan
+func (app *application) getLoansHandler(w http.ResponseWriter, r *http.Request) {
+	status := app.readStrings(r.URL.Query(), "status", "overdue")
+
+	v := validator.New()
+
+	v.Check(validator.PermittedValue(status, "overdue", "active"), "status", "must be overdue or active")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	loans, err := app.models.Loan.GetOutstandingLoans(app.contextGetUser(r).LibraryID, status == "overdue")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	formatLoanDates(loans)
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": loans}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func formatLoanDates(loans []*data.Loan) {
+	dateLayout := "02/01/2006"
+	for _, loan := range loans {
+		loan.DateAdded = loan.CreatedAt.UTC().Format(dateLayout)
+		loan.DateUpdated = loan.UpdatedAt.UTC().Format(dateLayout)
+	}
+}
@@ -21,7 +21,14 @@ const version = "1.0.0"
 type config struct {
 	port int
 	env  string
-	db   struct {
+	// devActivationTokens hands activation tokens back in the registration
+	// response, for working without a mailer. Ignored in production.
+	devActivationTokens bool
+	admin               struct {
+		name  string
+		email string
+	}
+	db struct {
 		dsn          string
 		maxOpenConns int
 		maxIdleConns int
@@ -66,6 +73,9 @@ func init() {
 	flag.IntVar(&cfg.port, "port", 5200, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
 	flag.StringVar(&cfg.db.dsn, "db-dsn", dsn, "MySQL dsn")
+	flag.BoolVar(&cfg.devActivationTokens, "dev-activation-tokens", false, "Return activation tokens when users register (ignored in production)")
+	flag.StringVar(&cfg.admin.email, "create-admin", "", "Create an activated admin with this email, the password is read from ADMIN_PASSWORD, and exit")
+	flag.StringVar(&cfg.admin.name, "admin-name", "Admin", "Name of the admin made with -create-admin")
 
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "MySQL max open connections")
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "MySQL max idle connections")
@@ -94,6 +104,15 @@ func main() {
 		models: data.NewModels(db),
 	}
 
+	if cfg.admin.email != "" {
+		err = app.createAdmin(cfg.admin.name, cfg.admin.email, os.Getenv("ADMIN_PASSWORD"))
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		logger.PrintInfo("admin created", map[string]string{"email": cfg.admin.email})
+		return
+	}
+
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.port),
 		Handler:      app.routes(),
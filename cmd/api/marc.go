@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/marc"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// exportBooksMARCHandler streams every book in the library as MARC 21 records in
+// the ISO 2709 exchange format, see marc.FromBook for the fields
+func (app *application) exportBooksMARCHandler(w http.ResponseWriter, r *http.Request) {
+	mw := marc.NewWriter(w)
+
+	app.streamBooks(w, r, "application/marc", "books.mrc", bookStream{
+		page: func(books []*data.Book) error {
+			for _, b := range books {
+				err := mw.Write(marc.FromBook(b))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// exportBooksMARCXMLHandler streams every book in the library as a MARCXML
+// collection
+func (app *application) exportBooksMARCXMLHandler(w http.ResponseWriter, r *http.Request) {
+	xw := marc.NewXMLWriter(w)
+
+	app.streamBooks(w, r, "application/marcxml+xml; charset=utf-8", "books.xml", bookStream{
+		page: func(books []*data.Book) error {
+			for _, b := range books {
+				err := xw.Write(marc.FromBook(b))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		end: xw.Close,
+	})
+}
+
+// importMARCHandler imports the books of a file of MARC 21 records, in the ISO 2709
+// format or as MARCXML, which is told apart by its first character. See
+// marc.ReadBook for the fields read, subjects become categories. The upload and
+// the report are the same as importBooksHandler's, rows being the records' numbers
+func (app *application) importMARCHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	dryRun := app.readBool(r.URL.Query(), "dry_run", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	content, err := app.readImportUpload(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var records []*marc.Record
+
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("<")) {
+		records, err = marc.ReadXML(bytes.NewReader(content))
+	} else {
+		records, err = marc.NewReader(bytes.NewReader(content)).ReadAll()
+	}
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("the file is not valid MARC: %v", err))
+		return
+	}
+
+	v.Check(len(records) > 0, "file", "must not be empty")
+	v.Check(len(records) <= maxImportRows, "file", fmt.Sprintf("must not have more than %d records", maxImportRows))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.writeImportResults(w, r, marcImports(records), dryRun)
+}
+
+// marcImports reads the records into books. Contributors keep their inverted
+// names, with the role in brackets the way the CSV import reads them.
+func marcImports(records []*marc.Record) []*bookImport {
+	imports := []*bookImport{}
+
+	for i, rec := range records {
+		br := marc.ReadBook(rec)
+
+		bi := &bookImport{
+			Row:        i + 1,
+			Categories: br.Subjects,
+			Publishers: br.Publishers,
+			ISBNs:      br.ISBNs,
+			Errors:     map[string]string{},
+		}
+
+		for _, c := range br.Contributors {
+			name := c.Name
+			if c.Role != data.RoleAuthor {
+				name = fmt.Sprintf("%s (%s)", name, c.Role)
+			}
+			bi.Authors = append(bi.Authors, name)
+		}
+
+		bi.Book = &data.Book{
+			Title:         br.Title,
+			Subtitle:      br.Subtitle,
+			ISBN:          br.ISBN,
+			PageCount:     br.PageCount,
+			PublishedDate: strings.TrimSpace(br.PublishedDate),
+			Description:   br.Description,
+		}
+
+		imports = append(imports, bi)
+	}
+
+	return imports
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/opds"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// opdsPageSize is the default number of entries in a page of a feed.
+const opdsPageSize = 25
+
+const (
+	opdsRoot   = "/opds"
+	opdsSearch = "/opds/search.xml"
+)
+
+// opdsRootHandler serves the start of the OPDS catalog, a navigation feed leading
+// to every book, the books by author, category and status, with a search through
+// the OpenSearch description. Books don't have files, their entries carry the
+// details and cover of the book
+func (app *application) opdsRootHandler(w http.ResponseWriter, r *http.Request) {
+	feed := app.opdsFeed(r, "Book catalogue")
+
+	feed.Entries = []*opds.Entry{
+		app.opdsNavigationEntry(r, feed, "All books", "Every book in the library", "/opds/books", opds.AcquisitionType),
+		app.opdsNavigationEntry(r, feed, "Authors", "Books by author", "/opds/authors", opds.NavigationType),
+		app.opdsNavigationEntry(r, feed, "Categories", "Books by category", "/opds/categories", opds.NavigationType),
+		app.opdsNavigationEntry(r, feed, "Reading status", "Books by reading status", "/opds/status", opds.NavigationType),
+	}
+
+	app.writeOPDS(w, r, opds.NavigationType, feed)
+}
+
+// opdsBooksHandler serves every book in the library, or the books with q in their
+// title when searching
+func (app *application) opdsBooksHandler(w http.ResponseWriter, r *http.Request) {
+	title := "All books"
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q != "" {
+		title = fmt.Sprintf("Search results for %q", q)
+	}
+
+	app.writeOPDSBooks(w, r, title, q, nil, nil, 0)
+}
+
+// opdsAuthorsHandler serves the authors with books, by last name
+func (app *application) opdsAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	filters, ok := app.readOPDSFilters(w, r)
+	if !ok {
+		return
+	}
+
+	authors, metadata, err := app.models.Author.GetAuthorsWithBooks(app.contextGetUser(r).LibraryID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	feed := app.opdsFeed(r, "Authors")
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: opdsRoot, Type: opds.NavigationType})
+
+	for _, a := range authors {
+		name := strings.TrimSpace(a.FirstName + " " + a.LastName)
+		content := fmt.Sprintf("%d books", a.AuthorBooks)
+		feed.Entries = append(feed.Entries, app.opdsNavigationEntry(r, feed, name, content, fmt.Sprintf("/opds/authors/%d", a.AuthorID), opds.AcquisitionType))
+	}
+
+	opdsPageLinks(feed, r, metadata, opds.NavigationType)
+
+	app.writeOPDS(w, r, opds.NavigationType, feed)
+}
+
+// opdsAuthorBooksHandler serves the books by an author
+func (app *application) opdsAuthorBooksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	author, err := app.models.Author.GetAuthor(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	title := strings.TrimSpace(author.FirstName + " " + author.LastName)
+
+	app.writeOPDSBooks(w, r, title, "", []string{strconv.FormatInt(id, 10)}, nil, 0)
+}
+
+// opdsCategoriesHandler serves the categories with books in the library, by name
+func (app *application) opdsCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	filters, ok := app.readOPDSFilters(w, r)
+	if !ok {
+		return
+	}
+
+	categories, metadata, err := app.models.Category.GetCategoriesPage(app.contextGetUser(r).LibraryID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	feed := app.opdsFeed(r, "Categories")
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: opdsRoot, Type: opds.NavigationType})
+
+	for _, c := range categories {
+		feed.Entries = append(feed.Entries, app.opdsNavigationEntry(r, feed, c.Name, "Books in "+c.Name, fmt.Sprintf("/opds/categories/%d", c.ID), opds.AcquisitionType))
+	}
+
+	opdsPageLinks(feed, r, metadata, opds.NavigationType)
+
+	app.writeOPDS(w, r, opds.NavigationType, feed)
+}
+
+// opdsCategoryBooksHandler serves the books in a category, including its
+// subcategories
+func (app *application) opdsCategoryBooksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.models.Category.GetCategory(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeOPDSBooks(w, r, category.Name, "", nil, []string{strconv.FormatInt(id, 10)}, 0)
+}
+
+// opdsStatusesHandler serves the reading statuses
+func (app *application) opdsStatusesHandler(w http.ResponseWriter, r *http.Request) {
+	feed := app.opdsFeed(r, "Reading status")
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: opdsRoot, Type: opds.NavigationType})
+
+	for i, name := range data.StatusNames {
+		feed.Entries = append(feed.Entries, app.opdsNavigationEntry(r, feed, name, "Books marked "+name, fmt.Sprintf("/opds/status/%d", i+1), opds.AcquisitionType))
+	}
+
+	app.writeOPDS(w, r, opds.NavigationType, feed)
+}
+
+// opdsStatusBooksHandler serves the books with a reading status, 1 to 3
+func (app *application) opdsStatusBooksHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := app.readIDParam(r)
+	if err != nil || status > int64(len(data.StatusNames)) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.writeOPDSBooks(w, r, data.StatusNames[status-1], "", nil, nil, int(status))
+}
+
+// opdsSearchDescriptionHandler serves the OpenSearch description of the title
+// search. Its template has to be an absolute URL
+func (app *application) opdsSearchDescriptionHandler(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	template := fmt.Sprintf("%s://%s/opds/books?q={searchTerms}", scheme, r.Host)
+
+	app.writeOPDS(w, r, opds.OpenSearchType, opds.NewOpenSearchDescription("Books", "Search the books by title", template))
+}
+
+// readOPDSFilters reads the page and page_size of a feed.
+func (app *application) readOPDSFilters(w http.ResponseWriter, r *http.Request) (data.Filters, bool) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", opdsPageSize, v),
+		Sort:         "title",
+		SortSafelist: []string{"title"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return filters, false
+	}
+
+	return filters, true
+}
+
+// writeOPDSBooks writes an acquisition feed of a page of the books matching the
+// title, authors, categories and status, see GetFilteredBooks.
+func (app *application) writeOPDSBooks(w http.ResponseWriter, r *http.Request, feedTitle string, title string, authors []string, categories []string, status int) {
+	filters, ok := app.readOPDSFilters(w, r)
+	if !ok {
+		return
+	}
+
+	books, metadata, err := app.models.Book.GetFilteredBooks(title, authors, categories, status, filters, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.loadBookLinks(books)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	feed := app.opdsFeed(r, feedTitle)
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: path.Dir(r.URL.Path), Type: opds.NavigationType})
+
+	for _, b := range books {
+		feed.Entries = append(feed.Entries, opdsBookEntry(b))
+	}
+
+	opdsPageLinks(feed, r, metadata, opds.AcquisitionType)
+
+	app.writeOPDS(w, r, opds.AcquisitionType, feed)
+}
+
+// opdsID makes the id of the feed at the path, from the library and the path, so
+// the feed keeps it between pages.
+func opdsID(user *data.User, feedPath string) string {
+	return fmt.Sprintf("urn:book_catalogue:library:%d%s", user.LibraryID, strings.ReplaceAll(feedPath, "/", ":"))
+}
+
+// opdsFeed returns a feed for the request with the links every feed has.
+func (app *application) opdsFeed(r *http.Request, title string) *opds.Feed {
+	feed := opds.NewFeed(opdsID(app.contextGetUser(r), r.URL.Path), title, time.Now())
+	feed.Author = &opds.Person{Name: "Book catalogue"}
+	feed.Links = []opds.Link{
+		{Rel: opds.RelSelf, Href: r.URL.RequestURI()},
+		{Rel: opds.RelStart, Href: opdsRoot, Type: opds.NavigationType},
+		{Rel: opds.RelSearch, Href: opdsSearch, Type: opds.OpenSearchType},
+	}
+
+	return feed
+}
+
+// writeOPDS writes a feed or OpenSearch description, with the media type.
+func (app *application) writeOPDS(w http.ResponseWriter, r *http.Request, contentType string, doc any) {
+	var buf bytes.Buffer
+
+	err := opds.Write(&buf, doc)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType+";charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// opdsNavigationEntry makes an entry leading to the feed at href, with the feed's
+// id.
+func (app *application) opdsNavigationEntry(r *http.Request, feed *opds.Feed, title, content, href, linkType string) *opds.Entry {
+	return &opds.Entry{
+		ID:      opdsID(app.contextGetUser(r), href),
+		Title:   title,
+		Updated: feed.Updated,
+		Content: opds.PlainText(content),
+		Links:   []opds.Link{{Rel: opds.RelSubsection, Href: href, Type: linkType}},
+	}
+}
+
+// opdsPageLinks adds the paging links and OpenSearch totals of the page to the
+// feed.
+func opdsPageLinks(feed *opds.Feed, r *http.Request, metadata data.Metadata, linkType string) {
+	if metadata.TotalRecords == 0 {
+		return
+	}
+
+	feed.TotalResults = metadata.TotalRecords
+	feed.ItemsPerPage = metadata.PageSize
+	feed.StartIndex = (metadata.CurrentPage-1)*metadata.PageSize + 1
+
+	pageLink := func(rel string, page int) opds.Link {
+		qs := r.URL.Query()
+		qs.Set("page", strconv.Itoa(page))
+		return opds.Link{Rel: rel, Href: r.URL.Path + "?" + qs.Encode(), Type: linkType}
+	}
+
+	feed.Links = append(feed.Links, pageLink(opds.RelFirst, metadata.FirstPage))
+	if metadata.CurrentPage > metadata.FirstPage {
+		feed.Links = append(feed.Links, pageLink(opds.RelPrevious, metadata.CurrentPage-1))
+	}
+	if metadata.CurrentPage < metadata.LastPage {
+		feed.Links = append(feed.Links, pageLink(opds.RelNext, metadata.CurrentPage+1))
+	}
+	feed.Links = append(feed.Links, pageLink(opds.RelLast, metadata.LastPage))
+}
+
+// opdsBookEntry makes the entry of a book with its links loaded. Contributors
+// with another role than author are Atom contributors.
+func opdsBookEntry(b *data.Book) *opds.Entry {
+	entry := &opds.Entry{
+		ID:      fmt.Sprintf("urn:book_catalogue:book:%d", b.ID),
+		Title:   b.Title,
+		Updated: opds.Timestamp(b.UpdatedAt),
+		Issued:  b.PublishedDate,
+		Summary: opds.PlainText(b.Description),
+		Links: []opds.Link{
+			{Rel: opds.RelAlternate, Href: fmt.Sprintf("/v1/books/%d", b.ID), Type: "application/json"},
+		},
+	}
+
+	if b.Subtitle != "" {
+		entry.Title += ": " + b.Subtitle
+	}
+
+	for _, a := range b.BookAuthors {
+		person := opds.Person{
+			Name: strings.TrimSpace(a.FirstName + " " + a.LastName),
+			URI:  fmt.Sprintf("/opds/authors/%d", a.AuthorID),
+		}
+
+		if a.Role == "" || a.Role == data.RoleAuthor {
+			entry.Authors = append(entry.Authors, person)
+		} else {
+			entry.Contributors = append(entry.Contributors, person)
+		}
+	}
+
+	if b.ISBN != "" {
+		entry.Identifiers = append(entry.Identifiers, "urn:isbn:"+b.ISBN)
+	}
+
+	for _, p := range b.BookPublishers {
+		entry.Publishers = append(entry.Publishers, p.Name)
+	}
+
+	for _, c := range b.BookCategories {
+		entry.Categories = append(entry.Categories, opds.Category{Term: c.Name, Label: c.Name})
+	}
+
+	if b.Image != "" {
+		imageType := "image/jpeg"
+		if u, err := url.Parse(b.Image); err == nil {
+			if t := mime.TypeByExtension(path.Ext(u.Path)); strings.HasPrefix(t, "image/") {
+				imageType = t
+			}
+		}
+
+		entry.Links = append(entry.Links,
+			opds.Link{Rel: opds.RelImage, Href: b.Image, Type: imageType},
+			opds.Link{Rel: opds.RelThumbnail, Href: b.Image, Type: imageType},
+		)
+	}
+
+	return entry
+}
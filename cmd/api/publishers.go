@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// createPublisherHandler creates new publisher
+func (app *application) createPublisherHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	publisher := &data.Publisher{
+		Name: input.Name,
+	}
+
+	v := validator.New()
+
+	if data.ValidatePublisher(v, publisher); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	publisherId, err := app.models.Publisher.Insert(publisher)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	publisherResult := fmt.Sprintf("%q has been added to your catalogue", publisher.Name)
+	jsonResponse := map[string]any{
+		"client_message": publisherResult,
+		"publisher_id":   publisherId,
+	}
+
+	err = app.writeToJSON(w, http.StatusCreated, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getPublishersHandler get all publishers
+func (app *application) getPublishersHandler(w http.ResponseWriter, r *http.Request) {
+
+	publishers, err := app.models.Publisher.GetPublishers()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	booksByPublishers, err := app.models.Publisher.GetBooksByPublishers(app.contextGetUser(r).LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	for _, pub := range publishers {
+		pub.DateAdded = pub.CreatedAt.UTC().Format(dateLayout)
+		pub.DateUpdated = pub.UpdatedAt.UTC().Format(dateLayout)
+
+		pub.BooksByPublisher = booksByPublishers[pub.ID]
+	}
+
+	sort.Slice(publishers, func(i, j int) bool {
+		return publishers[i].BooksByPublisher > publishers[j].BooksByPublisher
+	})
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": publishers}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getPublisherHandler get publisher by id
+func (app *application) getPublisherHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	publisher, err := app.models.Publisher.GetPublisher(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	publisher.DateAdded = publisher.CreatedAt.UTC().Format(dateLayout)
+	publisher.DateUpdated = publisher.UpdatedAt.UTC().Format(dateLayout)
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"publisher": publisher}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deletePublisherHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		ID []int `json:"ids"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !app.checkLibraryOnly(w, r, app.models.Publisher.UsedOutsideLibrary, toInt64s(input.ID)) {
+		return
+	}
+
+	for _, id := range input.ID {
+		err = app.models.Publisher.DeletePublisher(int64(id))
+		if err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"message": "publisher successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updatePublisherHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	publisher, err := app.models.Publisher.GetPublisher(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Id   int     `json:"id"`
+		Name *string `json:"name"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		publisher.Name = *input.Name
+	}
+
+	v := validator.New()
+
+	if data.ValidatePublisher(v, publisher); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.checkLibraryOnly(w, r, app.models.Publisher.UsedOutsideLibrary, []int64{publisher.ID}) {
+		return
+	}
+
+	err = app.models.Publisher.UpdatePublisher(publisher)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"client_message": "publisher name has been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// getBookQuotesHandler get the current user's quotes from a book
+func (app *application) getBookQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	quotes, err := app.models.Quote.GetBookQuotes(book.ID, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	formatQuoteDates(quotes...)
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": quotes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createQuoteHandler saves a quote or highlight from a book
+func (app *application) createQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Text     string `json:"text"`
+		Page     int    `json:"page"`
+		Location string `json:"location"`
+		Note     string `json:"note"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	quote := &data.Quote{
+		BookID:   book.ID,
+		UserID:   app.contextGetUser(r).ID,
+		Text:     input.Text,
+		Page:     input.Page,
+		Location: input.Location,
+		Note:     input.Note,
+	}
+
+	v := validator.New()
+
+	if data.ValidateQuote(v, quote); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	quoteId, err := app.models.Quote.Insert(quote)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"quote_id":       quoteId,
+		"client_message": "your quote has been saved",
+	}
+
+	err = app.writeToJSON(w, http.StatusCreated, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateQuoteHandler updates one of the current user's quotes
+func (app *application) updateQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	quote, ok := app.readOwnQuote(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Text     *string `json:"text"`
+		Page     *int    `json:"page"`
+		Location *string `json:"location"`
+		Note     *string `json:"note"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Text != nil {
+		quote.Text = *input.Text
+	}
+
+	if input.Page != nil {
+		quote.Page = *input.Page
+	}
+
+	if input.Location != nil {
+		quote.Location = *input.Location
+	}
+
+	if input.Note != nil {
+		quote.Note = *input.Note
+	}
+
+	v := validator.New()
+
+	if data.ValidateQuote(v, quote); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Quote.UpdateQuote(quote)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"client_message": "your quote has been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteQuoteHandler deletes one of the current user's quotes
+func (app *application) deleteQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	quote, ok := app.readOwnQuote(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Quote.DeleteQuote(quote.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"message": "quote successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// searchQuotesHandler searches the current user's quotes across the library,
+// without q it lists the latest quotes
+func (app *application) searchQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	q := app.readStrings(qs, "q", "")
+	limit := app.readInt(qs, "limit", 20, v)
+
+	v.Check(len(q) <= 255, "q", "must not be more than 255 characters long")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 100, "limit", "must be a maximum of 100")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	quotes, err := app.models.Quote.SearchQuotes(q, limit, user.ID, user.LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	formatQuoteDates(quotes...)
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": quotes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getRandomQuoteHandler picks one of the current user's quotes for the dashboard
+func (app *application) getRandomQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	quote, err := app.models.Quote.RandomQuote(user.ID, user.LibraryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	formatQuoteDates(quote)
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"quote": quote}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readOwnQuote loads the quote named in the route, making sure the book is in the
+// user's library and the quote was saved by the user. It writes the error response
+// itself and returns false when the quote can't be used.
+func (app *application) readOwnQuote(w http.ResponseWriter, r *http.Request) (*data.Quote, bool) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := app.readNamedIDParam(r, "quote_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	quote, err := app.models.Quote.GetQuote(id, book.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	// quotes are personal, someone else's quote is reported as missing
+	if quote.UserID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return quote, true
+}
+
+func formatQuoteDates(quotes ...*data.Quote) {
+	dateLayout := "02/01/2006"
+	for _, quote := range quotes {
+		quote.DateAdded = quote.CreatedAt.UTC().Format(dateLayout)
+		quote.DateUpdated = quote.UpdatedAt.UTC().Format(dateLayout)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// setBookStatusHandler sets the current user's reading status for a book, which
+// is their own and needs no write access to the catalogue
+func (app *application) setBookStatusHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Status int `json:"status"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateStatus(v, input.Status)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	book.Status = input.Status
+
+	err = app.models.Book.SetStatus(user.ID, book)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"book_status":    book.Status,
+		"client_message": "reading status has been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createReadingSessionHandler logs a reading session for the current user and moves
+// the book's status along
+func (app *application) createReadingSessionHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	var input struct {
+		StartedAt time.Time `json:"started_at"`
+		EndedAt   time.Time `json:"ended_at"`
+		StartPage int       `json:"start_page"`
+		EndPage   int       `json:"end_page"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	session := &data.ReadingSession{
+		BookID:    book.ID,
+		UserID:    user.ID,
+		StartedAt: input.StartedAt,
+		EndedAt:   input.EndedAt,
+		StartPage: input.StartPage,
+		EndPage:   input.EndPage,
+	}
+
+	v := validator.New()
+
+	if data.ValidateReadingSession(v, session, book); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.RunInTx(func(tx data.Models) error {
+		err := tx.Reading.InsertSession(session)
+		if err != nil {
+			return err
+		}
+
+		status := data.NextStatus(book.Status, book, session)
+		if status == book.Status {
+			return nil
+		}
+
+		book.Status = status
+		return tx.Book.SetStatus(user.ID, book)
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"session":        session,
+		"book_status":    book.Status,
+		"client_message": "reading session has been logged",
+	}
+
+	err = app.writeToJSON(w, http.StatusCreated, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getReadingSessionsHandler lists the current user's sessions for a book with the
+// progress they add up to
+func (app *application) getReadingSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	sessions, err := app.models.Reading.GetSessions(user.ID, book.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": sessions, "progress": data.Progress(book, sessions)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getStatusHistoryHandler lists the current user's status changes for a book
+func (app *application) getStatusHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	history, err := app.models.Reading.GetStatusHistory(user.ID, book.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"status": book.StatusName, "results": history}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// getBookReviewsHandler get all reviews of a book
+func (app *application) getBookReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	reviews, err := app.models.Review.GetBookReviews(book.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	for _, review := range reviews {
+		review.DateAdded = review.CreatedAt.UTC().Format(dateLayout)
+		review.DateUpdated = review.UpdatedAt.UTC().Format(dateLayout)
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"average_rating": book.AverageRating, "rating_count": book.RatingCount, "results": reviews}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createReviewHandler adds the current user's review of a book
+func (app *application) createReviewHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Rating       float64 `json:"rating"`
+		Review       string  `json:"review"`
+		Spoiler      bool    `json:"spoiler"`
+		DateFinished string  `json:"date_finished"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	review := &data.Review{
+		BookID:       book.ID,
+		UserID:       app.contextGetUser(r).ID,
+		Rating:       input.Rating,
+		Review:       input.Review,
+		Spoiler:      input.Spoiler,
+		DateFinished: input.DateFinished,
+	}
+
+	v := validator.New()
+
+	if data.ValidateReview(v, review); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reviewId, err := app.models.Review.Insert(review)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateReview):
+			v.AddError("book_id", "you have already reviewed this book")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"review_id":      reviewId,
+		"client_message": "your review has been added",
+	}
+
+	err = app.writeToJSON(w, http.StatusCreated, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateReviewHandler updates one of the current user's reviews
+func (app *application) updateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	review, ok := app.readOwnReview(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Rating       *float64 `json:"rating"`
+		Review       *string  `json:"review"`
+		Spoiler      *bool    `json:"spoiler"`
+		DateFinished *string  `json:"date_finished"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Rating != nil {
+		review.Rating = *input.Rating
+	}
+
+	if input.Review != nil {
+		review.Review = *input.Review
+	}
+
+	if input.Spoiler != nil {
+		review.Spoiler = *input.Spoiler
+	}
+
+	if input.DateFinished != nil {
+		review.DateFinished = *input.DateFinished
+	}
+
+	v := validator.New()
+
+	if data.ValidateReview(v, review); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Review.UpdateReview(review)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"client_message": "your review has been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteReviewHandler deletes one of the current user's reviews
+func (app *application) deleteReviewHandler(w http.ResponseWriter, r *http.Request) {
+	review, ok := app.readOwnReview(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Review.DeleteReview(review.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"message": "review successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readOwnReview loads the review named in the route, making sure the book is in
+// the user's library and the review was written by the user. It writes the error
+// response itself and returns false when the review can't be used.
+func (app *application) readOwnReview(w http.ResponseWriter, r *http.Request) (*data.Review, bool) {
+	book, ok := app.readBook(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := app.readNamedIDParam(r, "review_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	review, err := app.models.Review.GetReview(id, book.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if review.UserID != app.contextGetUser(r).ID {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+
+	return review, true
+}
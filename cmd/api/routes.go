@@ -17,26 +17,130 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
 
 	// books routes
-	router.HandlerFunc(http.MethodPost, "/v1/book", app.createBookHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/books", app.getBooksHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/books/:id", app.getBookHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/books", app.deleteBookHandler)
-	router.HandlerFunc(http.MethodPatch, "/v1/books/:id", app.updateBookHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/filter_books", app.listBooksHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/book", app.requirePermission("books:write", app.createBookHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/books", app.requirePermission("books:read", app.getBooksHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/books/:id", app.requirePermission("books:read", app.getBookHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/books", app.requirePermission("books:write", app.deleteBookHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/books/:id", app.requirePermission("books:write", app.updateBookHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/filter_books", app.requirePermission("books:read", app.listBooksHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/spend", app.requirePermission("books:read", app.getSpendHandler))
+
+	// bulk export and import routes
+	router.HandlerFunc(http.MethodGet, "/v1/export/books.csv", app.requirePermission("books:read", app.exportBooksCSVHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/export/books.mrc", app.requirePermission("books:read", app.exportBooksMARCHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/export/books.xml", app.requirePermission("books:read", app.exportBooksMARCXMLHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/import/books", app.requirePermission("books:write", app.importBooksHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/import/goodreads", app.requirePermission("books:write", app.importGoodreadsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/import/storygraph", app.requirePermission("books:write", app.importStoryGraphHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/import/marc", app.requirePermission("books:write", app.importMARCHandler))
+
+	// works routes
+	router.HandlerFunc(http.MethodGet, "/v1/works/:id", app.requirePermission("books:read", app.getWorkHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/works/:id", app.requirePermission("books:write", app.updateWorkHandler))
+
+	// reading routes, a user's reading data is personal and takes reading:write
+	// to change
+	router.HandlerFunc(http.MethodPut, "/v1/books/:id/status", app.requirePermission("reading:write", app.setBookStatusHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/books/:id/sessions", app.requirePermission("books:read", app.getReadingSessionsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/books/:id/sessions", app.requirePermission("reading:write", app.createReadingSessionHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/books/:id/status_history", app.requirePermission("books:read", app.getStatusHistoryHandler))
+
+	// reading goals routes
+	router.HandlerFunc(http.MethodGet, "/v1/goals", app.requirePermission("books:read", app.getGoalsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/goals/:year", app.requirePermission("books:read", app.getGoalHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/goals/:year", app.requirePermission("reading:write", app.setGoalHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/goals/:year", app.requirePermission("reading:write", app.deleteGoalHandler))
+
+	// reviews routes
+	router.HandlerFunc(http.MethodGet, "/v1/books/:id/reviews", app.requirePermission("books:read", app.getBookReviewsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/books/:id/reviews", app.requirePermission("reading:write", app.createReviewHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/books/:id/reviews/:review_id", app.requirePermission("reading:write", app.updateReviewHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/books/:id/reviews/:review_id", app.requirePermission("reading:write", app.deleteReviewHandler))
 
 	// authors routes
-	router.HandlerFunc(http.MethodPost, "/v1/author", app.createAuthorHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/authors", app.getAuthorsHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/authors/:id", app.getAuthorHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/authors", app.deleteAuthorHandler)
-	router.HandlerFunc(http.MethodPatch, "/v1/authors/:id", app.updateAuthorHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/author", app.requirePermission("authors:write", app.createAuthorHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/authors", app.requirePermission("books:read", app.getAuthorsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/authors/:id", app.requirePermission("books:read", app.getAuthorHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/authors", app.requirePermission("authors:write", app.deleteAuthorHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/authors/:id", app.requirePermission("authors:write", app.updateAuthorHandler))
 
 	// categories routes
-	router.HandlerFunc(http.MethodPost, "/v1/category", app.createCategoryHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/categories", app.getCategoriesHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/categories/:id", app.getCategoryHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/categories", app.deleteCategoryHandler)
-	router.HandlerFunc(http.MethodPatch, "/v1/categories/:id", app.updateCategoryHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/category", app.requirePermission("categories:write", app.createCategoryHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/categories", app.requirePermission("books:read", app.getCategoriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/categories/:id", app.requirePermission("books:read", app.getCategoryHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/category_tree", app.requirePermission("books:read", app.getCategoryTreeHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/categories", app.requirePermission("categories:write", app.deleteCategoryHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/categories/:id", app.requirePermission("categories:write", app.updateCategoryHandler))
+
+	// publishers routes
+	router.HandlerFunc(http.MethodPost, "/v1/publishers", app.requirePermission("books:write", app.createPublisherHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/publishers", app.requirePermission("books:read", app.getPublishersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/publishers/:id", app.requirePermission("books:read", app.getPublisherHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/publishers", app.requirePermission("books:write", app.deletePublisherHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/publishers/:id", app.requirePermission("books:write", app.updatePublisherHandler))
+
+	// quotes routes
+	router.HandlerFunc(http.MethodGet, "/v1/quotes", app.requirePermission("books:read", app.searchQuotesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/quotes/random", app.requirePermission("books:read", app.getRandomQuoteHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/books/:id/quotes", app.requirePermission("books:read", app.getBookQuotesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/books/:id/quotes", app.requirePermission("reading:write", app.createQuoteHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/books/:id/quotes/:quote_id", app.requirePermission("reading:write", app.updateQuoteHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/books/:id/quotes/:quote_id", app.requirePermission("reading:write", app.deleteQuoteHandler))
+
+	// loans routes
+	router.HandlerFunc(http.MethodGet, "/v1/loans", app.requirePermission("books:read", app.getLoansHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/books/:id/loans", app.requirePermission("books:read", app.getBookLoansHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/books/:id/loans", app.requirePermission("books:write", app.createLoanHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/books/:id/loans/:loan_id", app.requirePermission("books:write", app.updateLoanHandler))
+
+	// series routes
+	router.HandlerFunc(http.MethodPost, "/v1/series", app.requirePermission("books:write", app.createSeriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/series", app.requirePermission("books:read", app.getAllSeriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/series/:id", app.requirePermission("books:read", app.getSeriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/series/:id/next", app.requirePermission("books:read", app.getNextInSeriesHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/series", app.requirePermission("books:write", app.deleteSeriesHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/series/:id", app.requirePermission("books:write", app.updateSeriesHandler))
+
+	// shelves routes
+	router.HandlerFunc(http.MethodPost, "/v1/shelves", app.requirePermission("reading:write", app.createShelfHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/shelves", app.requirePermission("books:read", app.getShelvesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/shelves/:id", app.requirePermission("books:read", app.getShelfHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/shelves/:id", app.requirePermission("reading:write", app.updateShelfHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/shelves", app.requirePermission("reading:write", app.deleteShelfHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/shelves/:id/books", app.requirePermission("reading:write", app.updateShelfBooksHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/shelves/:id/share", app.requirePermission("reading:write", app.shareShelfHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/shelves/:id/share", app.requirePermission("reading:write", app.unshareShelfHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/shared/shelves/:token", app.getSharedShelfHandler)
+
+	// tags routes
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.requirePermission("books:read", app.getTagsHandler))
+
+	// search routes
+	router.HandlerFunc(http.MethodGet, "/v1/search", app.requirePermission("books:read", app.searchHandler))
+
+	// users routes
+	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/admin/users/:id/permissions", app.requirePermission("admin", app.updateUserPermissionsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/admin/users/:id/library", app.requirePermission("admin", app.updateUserLibraryHandler))
+
+	// library routes
+	router.HandlerFunc(http.MethodGet, "/v1/library", app.requirePermission("books:read", app.getLibraryHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/library", app.requirePermission("books:write", app.updateLibraryHandler))
+
+	// OPDS catalog routes, for e-reader apps signing in with Basic credentials
+	router.HandlerFunc(http.MethodGet, "/opds", app.requireBasicPermission("books:read", app.opdsRootHandler))
+	router.HandlerFunc(http.MethodGet, "/opds/search.xml", app.requireBasicPermission("books:read", app.opdsSearchDescriptionHandler))
+	router.HandlerFunc(http.MethodGet, "/opds/books", app.requireBasicPermission("books:read", app.opdsBooksHandler))
+	router.HandlerFunc(http.MethodGet, "/opds/authors", app.requireBasicPermission("books:read", app.opdsAuthorsHandler))
+	router.HandlerFunc(http.MethodGet, "/opds/authors/:id", app.requireBasicPermission("books:read", app.opdsAuthorBooksHandler))
+	router.HandlerFunc(http.MethodGet, "/opds/categories", app.requireBasicPermission("books:read", app.opdsCategoriesHandler))
+	router.HandlerFunc(http.MethodGet, "/opds/categories/:id", app.requireBasicPermission("books:read", app.opdsCategoryBooksHandler))
+	router.HandlerFunc(http.MethodGet, "/opds/status", app.requireBasicPermission("books:read", app.opdsStatusesHandler))
+	router.HandlerFunc(http.MethodGet, "/opds/status/:id", app.requireBasicPermission("books:read", app.opdsStatusBooksHandler))
+
+	// tokens routes
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
 
-	return app.recoverPanic(app.rateLimit(app.enableCORS(router)))
+	return app.recoverPanic(app.rateLimit(app.enableCORS(app.authenticate(router))))
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// searchHandler runs a full-text search across books, authors and categories
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	q := app.readStrings(qs, "q", "")
+	limit := app.readInt(qs, "limit", 20, v)
+
+	if data.ValidateSearch(v, q, limit); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	hits, err := app.models.Search.Search(q, limit, app.contextGetUser(r).LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": hits}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
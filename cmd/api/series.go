@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// createSeriesHandler creates new series
+func (app *application) createSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	series := &data.Series{
+		LibraryID:   app.contextGetUser(r).LibraryID,
+		Name:        input.Name,
+		Description: input.Description,
+	}
+
+	v := validator.New()
+
+	if data.ValidateSeries(v, series); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	seriesId, err := app.models.Series.Insert(series)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	seriesResult := fmt.Sprintf("%q has been added to your catalogue", series.Name)
+	jsonResponse := map[string]any{
+		"client_message": seriesResult,
+		"series_id":      seriesId,
+	}
+
+	err = app.writeToJSON(w, http.StatusCreated, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getAllSeriesHandler get all series
+func (app *application) getAllSeriesHandler(w http.ResponseWriter, r *http.Request) {
+
+	allSeries, err := app.models.Series.GetAllSeries(app.contextGetUser(r).LibraryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	for _, s := range allSeries {
+		s.DateAdded = s.CreatedAt.UTC().Format(dateLayout)
+		s.DateUpdated = s.UpdatedAt.UTC().Format(dateLayout)
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": allSeries}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getSeriesHandler get series by id together with its books in reading order
+func (app *application) getSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	series, ok := app.readSeries(w, r)
+	if !ok {
+		return
+	}
+
+	books, err := app.models.Book.GetSeriesBooks(series.ID, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	for _, b := range books {
+		b.DateAdded = b.CreatedAt.UTC().Format(dateLayout)
+		b.DateUpdated = b.UpdatedAt.UTC().Format(dateLayout)
+	}
+
+	series.DateAdded = series.CreatedAt.UTC().Format(dateLayout)
+	series.DateUpdated = series.UpdatedAt.UTC().Format(dateLayout)
+	series.BooksInSeries = len(books)
+	series.Books = books
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"series": series}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getNextInSeriesHandler returns the first book of the series the user hasn't read yet
+func (app *application) getNextInSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	series, ok := app.readSeries(w, r)
+	if !ok {
+		return
+	}
+
+	book, err := app.models.Book.NextUnreadInSeries(series.ID, app.contextGetUser(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "every book in this series has been read")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	book.DateAdded = book.CreatedAt.UTC().Format(dateLayout)
+	book.DateUpdated = book.UpdatedAt.UTC().Format(dateLayout)
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"book": book}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteSeriesHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		ID []int `json:"ids"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	libraryID := app.contextGetUser(r).LibraryID
+
+	for _, id := range input.ID {
+		err = app.models.Series.DeleteSeries(int64(id), libraryID)
+		if err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"message": "series successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	series, ok := app.readSeries(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Id          int     `json:"id"`
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		series.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		series.Description = *input.Description
+	}
+
+	v := validator.New()
+
+	if data.ValidateSeries(v, series); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Series.UpdateSeries(series)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"client_message": "series has been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readSeries loads the series named by the :id route parameter, writing the error
+// response itself when it can't be loaded.
+func (app *application) readSeries(w http.ResponseWriter, r *http.Request) (*data.Series, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	series, err := app.models.Series.GetSeries(id, app.contextGetUser(r).LibraryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return series, true
+}
+
+// checkSeriesInLibrary adds a validation error unless every series a book is being
+// added to belongs to the library.
+func (app *application) checkSeriesInLibrary(v *validator.Validator, entries []data.SeriesEntry, libraryID int64) error {
+	if !v.Valid() || len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+
+	inLibrary, err := app.models.Series.InLibrary(ids, libraryID)
+	if err != nil {
+		return err
+	}
+
+	v.Check(inLibrary, "series", "must only contain series in your library")
+	return nil
+}
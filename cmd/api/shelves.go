@@ -0,0 +1,353 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// createShelfHandler creates a new shelf for the current user
+func (app *application) createShelfHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	shelf := &data.Shelf{
+		UserID:      app.contextGetUser(r).ID,
+		Name:        input.Name,
+		Description: input.Description,
+	}
+
+	v := validator.New()
+
+	if data.ValidateShelf(v, shelf); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	shelfId, err := app.models.Shelf.Insert(shelf)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	shelfResult := fmt.Sprintf("%q shelf has been created", shelf.Name)
+	jsonResponse := map[string]any{
+		"client_message": shelfResult,
+		"shelf_id":       shelfId,
+	}
+
+	err = app.writeToJSON(w, http.StatusCreated, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getShelvesHandler get all shelves of the current user
+func (app *application) getShelvesHandler(w http.ResponseWriter, r *http.Request) {
+
+	shelves, err := app.models.Shelf.GetShelves(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	for _, shelf := range shelves {
+		shelf.DateAdded = shelf.CreatedAt.UTC().Format(dateLayout)
+		shelf.DateUpdated = shelf.UpdatedAt.UTC().Format(dateLayout)
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": shelves}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getShelfHandler get shelf by id together with its books in shelf order
+func (app *application) getShelfHandler(w http.ResponseWriter, r *http.Request) {
+	shelf, ok := app.readShelf(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeShelf(w, r, shelf, app.contextGetUser(r), false)
+}
+
+// getSharedShelfHandler is the public, read-only view of a shared shelf. Reading
+// statuses are left out as they belong to the shelf's owner.
+func (app *application) getSharedShelfHandler(w http.ResponseWriter, r *http.Request) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shelf, err := app.models.Shelf.GetShelfForShareToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeShelf(w, r, shelf, &data.User{LibraryID: shelf.LibraryID}, true)
+}
+
+func (app *application) updateShelfHandler(w http.ResponseWriter, r *http.Request) {
+	shelf, ok := app.readShelf(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Id          int     `json:"id"`
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		shelf.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		shelf.Description = *input.Description
+	}
+
+	v := validator.New()
+
+	if data.ValidateShelf(v, shelf); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Shelf.UpdateShelf(shelf)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"client_message": "shelf has been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateShelfBooksHandler adds, moves and removes books on a shelf. Books in
+// "books" are put at the given position (added if they aren't on the shelf yet),
+// books in "remove" are taken off.
+func (app *application) updateShelfBooksHandler(w http.ResponseWriter, r *http.Request) {
+	shelf, ok := app.readShelf(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Books  []data.ShelfEntry `json:"books"`
+		Remove []int64           `json:"remove"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateShelfEntries(v, input.Books, input.Remove)
+
+	bookIds := make([]int64, len(input.Books))
+	for i, entry := range input.Books {
+		bookIds[i] = entry.ID
+	}
+
+	if v.Valid() {
+		inLibrary, err := app.models.Book.InLibrary(bookIds, app.contextGetUser(r).LibraryID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		v.Check(inLibrary, "books", "must only contain books in your library")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.RunInTx(func(tx data.Models) error {
+		err := tx.Shelf.RemoveBooks(shelf.ID, input.Remove)
+		if err != nil {
+			return err
+		}
+
+		return tx.Shelf.SetBookPositions(shelf.ID, input.Books)
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeShelf(w, r, shelf, app.contextGetUser(r), false)
+}
+
+func (app *application) deleteShelfHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		ID []int `json:"ids"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	for _, id := range input.ID {
+		err = app.models.Shelf.DeleteShelf(int64(id), user.ID)
+		if err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"message": "shelf successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// shareShelfHandler creates a read-only link to the shelf. Sharing again replaces
+// the previous link.
+func (app *application) shareShelfHandler(w http.ResponseWriter, r *http.Request) {
+	shelf, ok := app.readShelf(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := app.models.Shelf.Share(shelf)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"share_token":    token,
+		"share_path":     "/v1/shared/shelves/" + token,
+		"client_message": "anyone with the link can now view this shelf",
+	}
+
+	err = app.writeToJSON(w, http.StatusCreated, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unshareShelfHandler revokes the shelf's read-only link
+func (app *application) unshareShelfHandler(w http.ResponseWriter, r *http.Request) {
+	shelf, ok := app.readShelf(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Shelf.Unshare(shelf)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"message": "shelf is no longer shared"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// writeShelf responds with the shelf and its books as seen by user. A shared shelf
+// leaves out the private details of its books.
+func (app *application) writeShelf(w http.ResponseWriter, r *http.Request, shelf *data.Shelf, user *data.User, shared bool) {
+	books, err := app.models.Book.GetShelfBooks(shelf.ID, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	for _, b := range books {
+		b.DateAdded = b.CreatedAt.UTC().Format(dateLayout)
+		b.DateUpdated = b.UpdatedAt.UTC().Format(dateLayout)
+
+		if shared {
+			b.HidePrivate()
+		}
+	}
+
+	shelf.DateAdded = shelf.CreatedAt.UTC().Format(dateLayout)
+	shelf.DateUpdated = shelf.UpdatedAt.UTC().Format(dateLayout)
+	shelf.BooksOnShelf = len(books)
+	shelf.Books = books
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"shelf": shelf}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readShelf loads the current user's shelf named by the :id route parameter,
+// writing the error response itself when it can't be loaded.
+func (app *application) readShelf(w http.ResponseWriter, r *http.Request) (*data.Shelf, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	shelf, err := app.models.Shelf.GetShelf(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return shelf, true
+}
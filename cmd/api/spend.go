@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// getSpendHandler sums the prices of the books bought for the library per month,
+// or with ?period=year per year, one total per currency
+func (app *application) getSpendHandler(w http.ResponseWriter, r *http.Request) {
+	period := app.readStrings(r.URL.Query(), "period", "month")
+
+	v := validator.New()
+
+	if v.Check(validator.PermittedValue(period, "month", "year"), "period", "must be month or year"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	spend, err := app.models.Book.GetSpend(app.contextGetUser(r).LibraryID, period == "year")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// totals in different currencies can't be added together
+	totals := map[string]float64{}
+	for _, s := range spend {
+		totals[s.Currency] += s.Total
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"period": period, "totals": totals, "results": spend}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+)
+
+// getTagsHandler get the tags of the library with their usage counts, optionally
+// only those starting with ?prefix= for autocompletion
+func (app *application) getTagsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	tags, err := app.models.Tag.GetTags(app.contextGetUser(r).LibraryID, app.readStrings(qs, "prefix", ""))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	for _, tag := range tags {
+		tag.DateAdded = tag.CreatedAt.UTC().Format(dateLayout)
+		tag.DateUpdated = tag.UpdatedAt.UTC().Format(dateLayout)
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"results": tags}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// registerUserHandler creates new, not yet activated user
+func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := &data.User{
+		Name:      input.Name,
+		Email:     input.Email,
+		Activated: false,
+	}
+
+	err = user.Password.Set(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateUser(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var token *data.Token
+	err = app.models.RunInTx(func(tx data.Models) error {
+		// every new user starts with a library of their own
+		libraryId, err := tx.Library.Insert(&data.Library{Name: user.Name + "'s library"})
+		if err != nil {
+			return err
+		}
+		user.LibraryID = int64(libraryId)
+
+		err = tx.User.Insert(user)
+		if err != nil {
+			return err
+		}
+
+		// new accounts can read the catalogue and keep their own reading data,
+		// changing the catalogue takes an admin granting more
+		err = tx.Permission.AddForUser(user.ID, data.PermissionBooksRead, data.PermissionReadingWrite)
+		if err != nil {
+			return err
+		}
+
+		token, err = tx.Token.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			v.AddError("email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// There is no mailer yet, so the activation token is written to the server
+	// log for an operator to pass on to the user. When the server runs with
+	// -dev-activation-tokens outside of production it is also handed straight
+	// back to the client.
+	app.logger.PrintInfo("activation token created", map[string]string{
+		"email":  user.Email,
+		"token":  token.Plaintext,
+		"expiry": token.Expiry.Format(time.RFC3339),
+	})
+
+	jsonResponse := map[string]any{
+		"user":           user,
+		"client_message": "your account has been created, please activate it",
+	}
+	if app.config.devActivationTokens && app.config.env != "production" {
+		jsonResponse["activation_token"] = token
+	}
+
+	err = app.writeToJSON(w, http.StatusCreated, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// activateUserHandler activates the user owning the activation token
+func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.User.GetForToken(data.ScopeActivation, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired activation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user.Activated = true
+
+	err = app.models.RunInTx(func(tx data.Models) error {
+		err := tx.User.Update(user)
+		if err != nil {
+			return err
+		}
+
+		return tx.Token.DeleteAllForUser(data.ScopeActivation, user.ID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateUserPermissionsHandler replaces the permissions of a user
+func (app *application) updateUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := app.models.User.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Permissions []string `json:"permissions"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.Permissions != nil, "permissions", "must be provided")
+	v.Check(validator.Unique(input.Permissions), "permissions", "must not contain duplicate values")
+	for _, code := range input.Permissions {
+		v.Check(validator.PermittedValue(code, data.AllPermissions...), "permissions", "contains an unknown permission")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.RunInTx(func(tx data.Models) error {
+		err := tx.Permission.RemoveAllForUser(user.ID)
+		if err != nil {
+			return err
+		}
+
+		return tx.Permission.AddForUser(user.ID, input.Permissions...)
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"user_id":        user.ID,
+		"permissions":    input.Permissions,
+		"client_message": "permissions have been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
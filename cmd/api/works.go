@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// getWorkHandler get work by id together with its authors, categories and all of
+// its editions
+func (app *application) getWorkHandler(w http.ResponseWriter, r *http.Request) {
+	work, ok := app.readWork(w, r)
+	if !ok {
+		return
+	}
+
+	authors, err := app.models.Author.GetWorkAuthors(work.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	work.WorkAuthors = authors
+
+	categories, err := app.models.Category.GetWorkCategories(work.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	work.WorkCategories = categories
+
+	editions, err := app.models.Book.GetWorkEditions(work.ID, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dateLayout := "02/01/2006"
+	for _, b := range editions {
+		b.DateAdded = b.CreatedAt.UTC().Format(dateLayout)
+		b.DateUpdated = b.UpdatedAt.UTC().Format(dateLayout)
+	}
+
+	work.DateAdded = work.CreatedAt.UTC().Format(dateLayout)
+	work.DateUpdated = work.UpdatedAt.UTC().Format(dateLayout)
+	work.Editions = editions
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"work": work}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateWorkHandler(w http.ResponseWriter, r *http.Request) {
+	work, ok := app.readWork(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Id          int     `json:"id"`
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Title != nil {
+		work.Title = *input.Title
+	}
+
+	if input.Description != nil {
+		work.Description = *input.Description
+	}
+
+	v := validator.New()
+
+	if data.ValidateWork(v, work); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Work.UpdateWork(work)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jsonResponse := map[string]any{
+		"client_message": "work has been updated",
+	}
+
+	err = app.writeToJSON(w, http.StatusOK, envelope{"success": jsonResponse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readWork loads the work named by the :id route parameter from the user's
+// library, writing the error response itself when it can't be loaded.
+func (app *application) readWork(w http.ResponseWriter, r *http.Request) (*data.Work, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	work, err := app.models.Work.GetWork(id, app.contextGetUser(r).LibraryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return work, true
+}
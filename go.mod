@@ -7,5 +7,6 @@ require github.com/julienschmidt/httprouter v1.3.0
 require (
 	github.com/go-sql-driver/mysql v1.7.0 // indirect
 	github.com/joho/godotenv v1.4.0 // indirect
+	golang.org/x/crypto v0.6.0 // indirect
 	golang.org/x/time v0.3.0 // indirect
 )
@@ -0,0 +1,382 @@
+// Package citation writes books as bibliographic references in the BibTeX, RIS and
+// CSL-JSON formats.
+package citation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+)
+
+// Citation formats and their media types.
+const (
+	BibTeX  = "bibtex"
+	RIS     = "ris"
+	CSLJSON = "csl-json"
+)
+
+var MediaTypes = map[string]string{
+	BibTeX:  "application/x-bibtex",
+	RIS:     "application/x-research-info-systems",
+	CSLJSON: "application/vnd.citationstyles.csl+json",
+}
+
+var (
+	yearRX = regexp.MustCompile(`\b\d{4}\b`)
+	dateRX = regexp.MustCompile(`^(\d{4})(?:-(\d{2})(?:-(\d{2}))?)?$`)
+)
+
+// stopWords are skipped when picking the title word of a citation key.
+var stopWords = map[string]bool{"a": true, "an": true, "the": true, "of": true, "on": true, "in": true, "and": true}
+
+// Write writes the books as format. The books need their BookAuthors and
+// BookPublishers loaded.
+func Write(w io.Writer, format string, books []*data.Book) error {
+	switch format {
+	case BibTeX:
+		return writeBibTeX(w, books)
+	case RIS:
+		return writeRIS(w, books)
+	case CSLJSON:
+		return writeCSLJSON(w, books)
+	default:
+		return fmt.Errorf("unknown citation format %q", format)
+	}
+}
+
+// Key returns the citation key of the book: the last name of its first author, the
+// year it was published and the first significant word of its title, followed by
+// the book id so the key is unique and doesn't change as the catalogue grows,
+// "herbert1965dune-42".
+func Key(book *data.Book) string {
+	var b strings.Builder
+
+	if authors := contributors(book, data.RoleAuthor); len(authors) > 0 {
+		b.WriteString(keyWord(authors[0].LastName))
+	}
+
+	b.WriteString(Year(book))
+
+	for _, word := range strings.Fields(book.Title) {
+		word = keyWord(word)
+		if word != "" && !stopWords[word] {
+			b.WriteString(word)
+			break
+		}
+	}
+
+	if b.Len() == 0 {
+		b.WriteString("book")
+	}
+
+	return b.String() + "-" + strconv.FormatInt(book.ID, 10)
+}
+
+// accents folds the common accented Latin letters onto their ASCII letter.
+var accents = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "č", "c", "ć", "c",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ę", "e", "ě", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i",
+	"ł", "l", "ñ", "n", "ń", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ř", "r", "ß", "ss", "š", "s", "ś", "s",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ů", "u",
+	"ý", "y", "ÿ", "y", "ž", "z", "ź", "z", "ż", "z",
+)
+
+// keyWord lower-cases the word and keeps only its ASCII letters and digits,
+// common accented letters lose their accents.
+func keyWord(word string) string {
+	var b strings.Builder
+
+	for _, r := range accents.Replace(strings.ToLower(word)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// Year returns the year of the published date, which is free text, or "" when it
+// has none.
+func Year(book *data.Book) string {
+	return yearRX.FindString(book.PublishedDate)
+}
+
+// contributors returns the book's contributors with the role, in order.
+func contributors(book *data.Book, role string) []*data.Author {
+	authors := []*data.Author{}
+
+	for _, a := range book.BookAuthors {
+		if a.Role == role || (a.Role == "" && role == data.RoleAuthor) {
+			authors = append(authors, a)
+		}
+	}
+
+	return authors
+}
+
+func publisherNames(book *data.Book) []string {
+	names := []string{}
+	for _, p := range book.BookPublishers {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// fullTitle joins the title and subtitle the way they're cited, "Title: Subtitle".
+func fullTitle(book *data.Book) string {
+	if book.Subtitle == "" {
+		return book.Title
+	}
+	return book.Title + ": " + book.Subtitle
+}
+
+// bibTeXEscapes are the characters with a special meaning in BibTeX values.
+var bibTeXEscapes = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+func bibTeXEscape(s string) string {
+	return bibTeXEscapes.Replace(strings.Join(strings.Fields(s), " "))
+}
+
+// bibTeXNames joins the names as "Last, First and Last, First". Names are braced
+// so BibTeX doesn't split them again.
+func bibTeXNames(authors []*data.Author) string {
+	names := make([]string, len(authors))
+
+	for i, a := range authors {
+		if a.FirstName == "" || a.LastName == "" {
+			names[i] = "{" + bibTeXEscape(strings.TrimSpace(a.FirstName+" "+a.LastName)) + "}"
+			continue
+		}
+		names[i] = "{" + bibTeXEscape(a.LastName) + "}, " + bibTeXEscape(a.FirstName)
+	}
+
+	return strings.Join(names, " and ")
+}
+
+func writeBibTeX(w io.Writer, books []*data.Book) error {
+	for i, book := range books {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+
+		fields := [][2]string{
+			// the extra braces keep the title's capitalisation
+			{"title", "{" + bibTeXEscape(fullTitle(book)) + "}"},
+		}
+
+		if authors := contributors(book, data.RoleAuthor); len(authors) > 0 {
+			fields = append(fields, [2]string{"author", bibTeXNames(authors)})
+		}
+		if editors := contributors(book, data.RoleEditor); len(editors) > 0 {
+			fields = append(fields, [2]string{"editor", bibTeXNames(editors)})
+		}
+		if translators := contributors(book, data.RoleTranslator); len(translators) > 0 {
+			fields = append(fields, [2]string{"translator", bibTeXNames(translators)})
+		}
+		if publishers := publisherNames(book); len(publishers) > 0 {
+			escaped := make([]string, len(publishers))
+			for i, p := range publishers {
+				escaped[i] = "{" + bibTeXEscape(p) + "}"
+			}
+			fields = append(fields, [2]string{"publisher", strings.Join(escaped, " and ")})
+		}
+		if year := Year(book); year != "" {
+			fields = append(fields, [2]string{"year", year})
+		}
+		if book.ISBN != "" {
+			fields = append(fields, [2]string{"isbn", bibTeXEscape(book.ISBN)})
+		}
+		if book.PageCount > 0 {
+			fields = append(fields, [2]string{"pagetotal", strconv.Itoa(book.PageCount)})
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "@book{%s,\n", Key(book))
+		for j, field := range fields {
+			fmt.Fprintf(&b, "  %s = {%s}", field[0], field[1])
+			if j < len(fields)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// risValue keeps a RIS value on one line, a tag's value ends at the line break.
+func risValue(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// risName formats a name as RIS expects, "Last, First".
+func risName(a *data.Author) string {
+	if a.FirstName == "" || a.LastName == "" {
+		return risValue(strings.TrimSpace(a.FirstName + " " + a.LastName))
+	}
+	return risValue(a.LastName + ", " + a.FirstName)
+}
+
+func writeRIS(w io.Writer, books []*data.Book) error {
+	for _, book := range books {
+		var b strings.Builder
+
+		tag := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "%s  - %s\r\n", name, value)
+			}
+		}
+
+		tag("TY", "BOOK")
+		tag("ID", Key(book))
+		tag("TI", risValue(book.Title))
+		if book.Subtitle != "" {
+			tag("ST", risValue(book.Subtitle))
+		}
+		for _, a := range contributors(book, data.RoleAuthor) {
+			tag("AU", risName(a))
+		}
+		for _, a := range contributors(book, data.RoleEditor) {
+			tag("A2", risName(a))
+		}
+		for _, a := range contributors(book, data.RoleTranslator) {
+			tag("A4", risName(a))
+		}
+		for _, p := range publisherNames(book) {
+			tag("PB", risValue(p))
+		}
+		tag("PY", Year(book))
+		tag("SN", risValue(book.ISBN))
+		if book.PageCount > 0 {
+			tag("SP", strconv.Itoa(book.PageCount))
+		}
+		b.WriteString("ER  - \r\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts,omitempty"`
+	Raw       string  `json:"raw,omitempty"`
+}
+
+type cslItem struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Title         string    `json:"title"`
+	Author        []cslName `json:"author,omitempty"`
+	Editor        []cslName `json:"editor,omitempty"`
+	Translator    []cslName `json:"translator,omitempty"`
+	Publisher     string    `json:"publisher,omitempty"`
+	Issued        *cslDate  `json:"issued,omitempty"`
+	ISBN          string    `json:"ISBN,omitempty"`
+	NumberOfPages string    `json:"number-of-pages,omitempty"`
+}
+
+func cslNames(authors []*data.Author) []cslName {
+	names := []cslName{}
+
+	for _, a := range authors {
+		if a.FirstName == "" || a.LastName == "" {
+			names = append(names, cslName{Literal: strings.TrimSpace(a.FirstName + " " + a.LastName)})
+			continue
+		}
+		names = append(names, cslName{Family: a.LastName, Given: a.FirstName})
+	}
+
+	return names
+}
+
+// cslIssued reads the published date into CSL date parts, dates that aren't
+// YYYY, YYYY-MM or YYYY-MM-DD are passed on raw.
+func cslIssued(published string) *cslDate {
+	published = strings.TrimSpace(published)
+	if published == "" {
+		return nil
+	}
+
+	match := dateRX.FindStringSubmatch(published)
+	if match == nil {
+		return &cslDate{Raw: published}
+	}
+
+	parts := []int{}
+	for _, part := range match[1:] {
+		if part == "" {
+			break
+		}
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, n)
+	}
+
+	return &cslDate{DateParts: [][]int{parts}}
+}
+
+func writeCSLJSON(w io.Writer, books []*data.Book) error {
+	items := make([]cslItem, len(books))
+
+	for i, book := range books {
+		items[i] = cslItem{
+			ID:         Key(book),
+			Type:       "book",
+			Title:      fullTitle(book),
+			Author:     cslNames(contributors(book, data.RoleAuthor)),
+			Editor:     cslNames(contributors(book, data.RoleEditor)),
+			Translator: cslNames(contributors(book, data.RoleTranslator)),
+			Publisher:  strings.Join(publisherNames(book), "; "),
+			Issued:     cslIssued(book.PublishedDate),
+			ISBN:       book.ISBN,
+		}
+
+		if book.PageCount > 0 {
+			items[i].NumberOfPages = strconv.Itoa(book.PageCount)
+		}
+	}
+
+	js, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(js)
+	return err
+}
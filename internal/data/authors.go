@@ -3,32 +3,109 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// Contributor roles. Every contributor is attached to the work with their role.
+const (
+	RoleAuthor      = "author"
+	RoleTranslator  = "translator"
+	RoleEditor      = "editor"
+	RoleIllustrator = "illustrator"
+	RoleNarrator    = "narrator"
 )
 
+var ContributorRoles = []string{RoleAuthor, RoleTranslator, RoleEditor, RoleIllustrator, RoleNarrator}
+
 type Author struct {
-	AuthorID    int64     `json:"id"`
-	FirstName   string    `json:"first_name"`
-	LastName    string    `json:"last_name"`
-	AuthorName  string    `json:"author_name"`
-	AuthorBooks int       `json:"author_books,omitempty"`
-	Description string    `json:"description,omitempty"`
-	DateAdded   string    `json:"date_added"`
-	DateUpdated string    `json:"date_updated"`
-	CreatedAt   time.Time `json:"-"`
-	UpdatedAt   time.Time `json:"-"`
+	AuthorID    int64              `json:"id"`
+	FirstName   string             `json:"first_name"`
+	LastName    string             `json:"last_name"`
+	AuthorName  string             `json:"author_name"`
+	AuthorBooks int                `json:"author_books,omitempty"`
+	Role        string             `json:"role,omitempty"`
+	BooksByRole map[string][]*Book `json:"books_by_role,omitempty"`
+	Description string             `json:"description,omitempty"`
+	DateAdded   string             `json:"date_added"`
+	DateUpdated string             `json:"date_updated"`
+	CreatedAt   time.Time          `json:"-"`
+	UpdatedAt   time.Time          `json:"-"`
 }
 
-type BookAuthor struct {
-	BookId    int64     `json:"book_id"`
+// WorkAuthor links an author to a work, and so to every edition of it, in one of
+// the contributor roles.
+type WorkAuthor struct {
+	WorkId    int64     `json:"work_id"`
 	AuthorId  int64     `json:"author_id"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
 
+// Contributor is an author and their role as sent in the book create/update input.
+type Contributor struct {
+	ID   int64  `json:"id"`
+	Role string `json:"role"`
+}
+
+// UnmarshalJSON also accepts a bare author id, which is read as that author with
+// the author role.
+func (c *Contributor) UnmarshalJSON(b []byte) error {
+	var id int64
+	if err := json.Unmarshal(b, &id); err == nil {
+		*c = Contributor{ID: id, Role: RoleAuthor}
+		return nil
+	}
+
+	type contributor Contributor
+	var cont contributor
+
+	err := json.Unmarshal(b, &cont)
+	if err != nil {
+		return err
+	}
+	if cont.Role == "" {
+		cont.Role = RoleAuthor
+	}
+
+	*c = Contributor(cont)
+	return nil
+}
+
+func ValidateContributors(v *validator.Validator, contributors []Contributor) {
+	seen := make(map[Contributor]bool, len(contributors))
+
+	for _, c := range contributors {
+		v.Check(c.ID > 0, "authors", "must contain valid author ids")
+		v.Check(validator.PermittedValue(c.Role, ContributorRoles...), "authors", "role must be one of author, translator, editor, illustrator or narrator")
+		v.Check(!seen[c], "authors", "must not contain duplicate values")
+		seen[c] = true
+	}
+}
+
+// SplitAuthorName splits a full name into first and last name for a new author.
+// "Last, First" is understood, otherwise the last word is the last name.
+func SplitAuthorName(name string) (first, last string) {
+	name = strings.Join(strings.Fields(name), " ")
+
+	if i := strings.Index(name, ","); i >= 0 {
+		return strings.TrimSpace(name[i+1:]), strings.TrimSpace(name[:i])
+	}
+
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+
+	return name, ""
+}
+
 type AuthorModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
 func (a *AuthorModel) Insert(author *Author) (int, error) {
@@ -54,8 +131,12 @@ func (a *AuthorModel) Insert(author *Author) (int, error) {
 
 }
 
-func (a *AuthorModel) InsertBookAuthors(ba []BookAuthor) (int, error) {
-	query := `INSERT INTO cg_book_authors (book_id, author_id, created_at, updated_at) VALUES`
+func (a *AuthorModel) InsertWorkAuthors(wa []WorkAuthor) (int, error) {
+	if len(wa) == 0 {
+		return 0, nil
+	}
+
+	query := `INSERT INTO cg_work_authors (work_id, author_id, role, created_at, updated_at) VALUES`
 
 	args := []any{}
 
@@ -63,14 +144,14 @@ func (a *AuthorModel) InsertBookAuthors(ba []BookAuthor) (int, error) {
 
 	defer cancel()
 
-	for _, v := range ba {
-		args = append(args, v.BookId, v.AuthorId)
-		numFields := 1
-
-		for j := 0; j < numFields; j++ {
-			query += `(?,` + `?` + `, UTC_TIMESTAMP(), UTC_TIMESTAMP()),`
+	for _, v := range wa {
+		role := v.Role
+		if role == "" {
+			role = RoleAuthor
 		}
-		query = query[:len(query)-1] + `,`
+
+		args = append(args, v.WorkId, v.AuthorId, role)
+		query += `(?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP()),`
 	}
 	query = query[:len(query)-1]
 
@@ -120,9 +201,11 @@ func (a *AuthorModel) GetAuthors() ([]*Author, error) {
 }
 
 func (a *AuthorModel) GetBookAuthors(id int64) ([]*Author, error) {
-	query := `SELECT CONCAT(a.first_name, ' ', a.last_name) as author_name, a.id, a.first_name, a.last_name, a.description, a.created_at, a.updated_at FROM cg_authors a
-						LEFT JOIN cg_book_authors bk  ON bk.author_id = a.id
-						WHERE bk.book_id = ?`
+	query := `SELECT CONCAT(a.first_name, ' ', a.last_name) as author_name, a.id, a.first_name, a.last_name, a.description, wa.role, a.created_at, a.updated_at FROM cg_authors a
+						INNER JOIN cg_work_authors wa ON wa.author_id = a.id
+						INNER JOIN cg_books b ON b.work_id = wa.work_id
+						WHERE b.id = ?
+						ORDER BY wa.role <> 'author', wa.role, author_name`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
@@ -140,7 +223,7 @@ func (a *AuthorModel) GetBookAuthors(id int64) ([]*Author, error) {
 	for results.Next() {
 		auth := &Author{}
 
-		err := results.Scan(&auth.AuthorName, &auth.AuthorID, &auth.FirstName, &auth.LastName, &auth.Description, &auth.CreatedAt, &auth.UpdatedAt)
+		err := results.Scan(&auth.AuthorName, &auth.AuthorID, &auth.FirstName, &auth.LastName, &auth.Description, &auth.Role, &auth.CreatedAt, &auth.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -151,6 +234,43 @@ func (a *AuthorModel) GetBookAuthors(id int64) ([]*Author, error) {
 
 }
 
+// GetWorkAuthors returns the authors and other contributors of the work.
+func (a *AuthorModel) GetWorkAuthors(workID int64) ([]*Author, error) {
+	query := `SELECT CONCAT(a.first_name, ' ', a.last_name) as author_name, a.id, a.first_name, a.last_name, a.description, wa.role, a.created_at, a.updated_at FROM cg_authors a
+						INNER JOIN cg_work_authors wa ON wa.author_id = a.id
+						WHERE wa.work_id = ?
+						ORDER BY wa.role <> 'author', wa.role, author_name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := a.DB.QueryContext(ctx, query, workID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer results.Close()
+
+	authors := []*Author{}
+
+	for results.Next() {
+		auth := &Author{}
+
+		err := results.Scan(&auth.AuthorName, &auth.AuthorID, &auth.FirstName, &auth.LastName, &auth.Description, &auth.Role, &auth.CreatedAt, &auth.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, auth)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, err
+	}
+
+	return authors, nil
+}
+
 func (a *AuthorModel) DeleteAuthor(id int64) error {
 	if id < 0 {
 		return ErrRecordNotFound
@@ -206,6 +326,56 @@ func (a *AuthorModel) GetAuthor(id int64) (*Author, error) {
 
 }
 
+// GetAuthorIDByName finds an author by full name, "First Last", ignoring case.
+// When several authors share the name the oldest one is returned. The CSV and MARC
+// imports match author names to existing authors with it.
+func (a *AuthorModel) GetAuthorIDByName(name string) (int64, error) {
+	query := `SELECT id FROM cg_authors WHERE LOWER(TRIM(CONCAT(first_name, ' ', last_name))) = LOWER(TRIM(?)) ORDER BY id LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	var id int64
+
+	err := a.DB.QueryRowContext(ctx, query, name).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+// UsedOutsideLibrary reports whether any of the authors in ids is linked to books in
+// another library than libraryID.
+func (a *AuthorModel) UsedOutsideLibrary(ids []int64, libraryID int64) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT EXISTS (SELECT 1 FROM cg_work_authors wa INNER JOIN cg_books b ON b.work_id = wa.work_id WHERE wa.author_id IN (` + in + `) AND b.library_id <> ?)`
+
+	var used bool
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := a.DB.QueryRowContext(ctx, query, append(args, libraryID)...).Scan(&used)
+	if err != nil {
+		return false, err
+	}
+
+	return used, nil
+}
+
 func (a *AuthorModel) UpdateAuthor(author *Author) error {
 	query := `UPDATE cg_authors SET first_name = ?, last_name = ?, description = ?, updated_at = UTC_TIMESTAMP() WHERE id = ?`
 
@@ -220,10 +390,11 @@ func (a *AuthorModel) UpdateAuthor(author *Author) error {
 	return nil
 }
 
-func (a *AuthorModel) GetAuthorNumberOfBooks(id int64) (int, error) {
+// GetAuthorNumberOfBooks counts the author's books in the library.
+func (a *AuthorModel) GetAuthorNumberOfBooks(id int64, libraryID int64) (int, error) {
 	query := `SELECT COUNT(b.id) FROM cg_books b
-						LEFT JOIN cg_book_authors ba ON ba.book_id = b.id
-						WHERE ba.author_id = ?`
+						INNER JOIN cg_work_authors wa ON wa.work_id = b.work_id
+						WHERE wa.author_id = ? AND wa.role = 'author' AND b.library_id = ?`
 
 	var bookAuthorNumber int
 
@@ -231,7 +402,7 @@ func (a *AuthorModel) GetAuthorNumberOfBooks(id int64) (int, error) {
 
 	defer cancel()
 
-	err := a.DB.QueryRowContext(ctx, query, id).Scan(&bookAuthorNumber)
+	err := a.DB.QueryRowContext(ctx, query, id, libraryID).Scan(&bookAuthorNumber)
 	if err != nil {
 		return 0, err
 	}
@@ -240,12 +411,12 @@ func (a *AuthorModel) GetAuthorNumberOfBooks(id int64) (int, error) {
 
 }
 
-func (a *AuthorModel) DeleteBookAuthors(id int64) error {
+func (a *AuthorModel) DeleteWorkAuthors(id int64) error {
 	if id < 0 {
 		return ErrRecordNotFound
 	}
 
-	query := `DELETE FROM cg_book_authors WHERE book_id = ?`
+	query := `DELETE FROM cg_work_authors WHERE work_id = ?`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
@@ -267,3 +438,132 @@ func (a *AuthorModel) DeleteBookAuthors(id int64) error {
 
 	return nil
 }
+
+// GetAuthorsForBooks loads the authors and other contributors of every book in ids
+// with a single query, keyed by book id.
+func (a *AuthorModel) GetAuthorsForBooks(ids []int64) (map[int64][]*Author, error) {
+	authors := make(map[int64][]*Author, len(ids))
+	if len(ids) == 0 {
+		return authors, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT b.id, CONCAT(a.first_name, ' ', a.last_name) as author_name, a.id, a.first_name, a.last_name, a.description, wa.role, a.created_at, a.updated_at FROM cg_authors a
+						INNER JOIN cg_work_authors wa ON wa.author_id = a.id
+						INNER JOIN cg_books b ON b.work_id = wa.work_id
+						WHERE b.id IN (` + in + `)
+						ORDER BY b.id, wa.role <> 'author', wa.role, author_name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := a.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var bookId int64
+		auth := &Author{}
+
+		err := results.Scan(&bookId, &auth.AuthorName, &auth.AuthorID, &auth.FirstName, &auth.LastName, &auth.Description, &auth.Role, &auth.CreatedAt, &auth.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		authors[bookId] = append(authors[bookId], auth)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, err
+	}
+
+	return authors, nil
+}
+
+// GetAuthorsWithBooks returns a page of the authors with books in the library,
+// ordered by last and first name, with their number of books.
+func (a *AuthorModel) GetAuthorsWithBooks(libraryID int64, filters Filters) ([]*Author, Metadata, error) {
+	query := `SELECT COUNT(*) OVER(), a.id, CONCAT(a.first_name, ' ', a.last_name) as author_name, a.first_name, a.last_name, a.description, COUNT(DISTINCT b.id), a.created_at, a.updated_at FROM cg_authors a
+						INNER JOIN cg_work_authors wa ON wa.author_id = a.id
+						INNER JOIN cg_books b ON b.work_id = wa.work_id
+						WHERE wa.role = 'author' AND b.library_id = ?
+						GROUP BY a.id
+						ORDER BY a.last_name, a.first_name, a.id
+						LIMIT ? OFFSET ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := a.DB.QueryContext(ctx, query, libraryID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	authors := []*Author{}
+
+	for rows.Next() {
+		auth := &Author{}
+
+		err := rows.Scan(&totalRecords, &auth.AuthorID, &auth.AuthorName, &auth.FirstName, &auth.LastName, &auth.Description, &auth.AuthorBooks, &auth.CreatedAt, &auth.UpdatedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		authors = append(authors, auth)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return authors, metadata, nil
+}
+
+// GetAuthorsNumberOfBooks counts the books in the library of every author in one
+// GROUP BY query, keyed by author id. Authors without books are missing from the
+// map.
+func (a *AuthorModel) GetAuthorsNumberOfBooks(libraryID int64) (map[int64]int, error) {
+	query := `SELECT wa.author_id, COUNT(DISTINCT b.id) FROM cg_work_authors wa
+						INNER JOIN cg_books b ON b.work_id = wa.work_id
+						WHERE wa.role = 'author' AND b.library_id = ?
+						GROUP BY wa.author_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := a.DB.QueryContext(ctx, query, libraryID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := map[int64]int{}
+
+	for rows.Next() {
+		var authorId int64
+		var count int
+
+		err := rows.Scan(&authorId, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[authorId] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,32 +15,52 @@ import (
 )
 
 type Book struct {
-	ID             int64       `json:"id"`
-	Title          string      `json:"title"`
-	Subtitle       string      `json:"subtitle"`
-	Description    string      `json:"description"`
-	Image          string      `json:"image"`
-	ISBN           string      `json:"isbn"`
-	PageCount      int         `json:"page_count"`
-	PublishedDate  string      `json:"published_date"`
-	Status         int         `json:"status,omitempty"`
-	StatusName     string      `json:"status_name"`
-	StatusID       int         `json:"status_id"`
-	Authors        []int       `json:"authors,omitempty"`
-	Categories     []int       `json:"categories,omitempty"`
-	BookCategories []*Category `json:"book_categories"`
-	BookAuthors    []*Author   `json:"book_authors"`
-	DateAdded      string      `json:"date_added"`
-	DateUpdated    string      `json:"date_updated"`
-	CreatedAt      time.Time   `json:"-"`
-	UpdatedAt      time.Time   `json:"-"`
+	ID             int64         `json:"id"`
+	LibraryID      int64         `json:"library_id"`
+	WorkID         int64         `json:"work_id"`
+	Title          string        `json:"title"`
+	Subtitle       string        `json:"subtitle"`
+	Description    string        `json:"description"`
+	Image          string        `json:"image"`
+	ISBN           string        `json:"isbn"`
+	PageCount      int           `json:"page_count"`
+	PublishedDate  string        `json:"published_date"`
+	Status         int           `json:"status,omitempty"`
+	StatusName     string        `json:"status_name"`
+	StatusID       int           `json:"status_id"`
+	AverageRating  float64       `json:"average_rating"`
+	RatingCount    int           `json:"rating_count"`
+	LentOut        bool          `json:"lent_out"`
+	Borrower       string        `json:"borrower,omitempty"`
+	Ownership      string        `json:"ownership"`
+	Format         string        `json:"format,omitempty"`
+	PurchaseDate   string        `json:"purchase_date,omitempty"`
+	Price          *float64      `json:"price,omitempty"`
+	Currency       string        `json:"currency,omitempty"`
+	Vendor         string        `json:"vendor,omitempty"`
+	Authors        []int         `json:"authors,omitempty"`
+	Categories     []int         `json:"categories,omitempty"`
+	Publishers     []int         `json:"publishers,omitempty"`
+	BookCategories []*Category   `json:"book_categories"`
+	BookAuthors    []*Author     `json:"book_authors"`
+	BookPublishers []*Publisher  `json:"book_publishers"`
+	BookSeries     []*BookSeries `json:"book_series"`
+	SeriesPosition *float64      `json:"series_position,omitempty"`
+	Editions       []*Book       `json:"editions,omitempty"`
+	Tags           []string      `json:"tags"`
+	Shelves        []*BookShelf  `json:"shelves,omitempty"`
+	ShelfPosition  *int          `json:"shelf_position,omitempty"`
+	DateAdded      string        `json:"date_added"`
+	DateUpdated    string        `json:"date_updated"`
+	CreatedAt      time.Time     `json:"-"`
+	UpdatedAt      time.Time     `json:"-"`
 }
 
 func ValidateBook(v *validator.Validator, book *Book) {
 
 	v.Check(book.Title != "", "title", "Title cannot be empty")
 
-	//	v.Check(book.Status > 0, "status", "Invalid Status")
+	ValidateAcquisition(v, book)
 
 	// v.Check(book.Authors != nil, "authors", "must be provided")
 	// v.Check(len(book.Authors) >= 1, "authors", "must contain at least 1 author")
@@ -48,16 +71,130 @@ func ValidateBook(v *validator.Validator, book *Book) {
 	// v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
 }
 
+// Ownership states, a wishlisted book is one we intend to buy.
+const (
+	OwnershipWishlist  = "wishlist"
+	OwnershipOwned     = "owned"
+	OwnershipSold      = "sold"
+	OwnershipGivenAway = "given_away"
+)
+
+var (
+	Ownerships  = []string{OwnershipWishlist, OwnershipOwned, OwnershipSold, OwnershipGivenAway}
+	BookFormats = []string{"hardcover", "paperback", "ebook", "audiobook"}
+)
+
+var currencyRX = regexp.MustCompile("^[A-Z]{3}$")
+
+// ValidateAcquisition checks the ownership and purchase details of the book. A
+// price needs a currency (an ISO 4217 code) and a wishlisted book can't have
+// been bought yet.
+func ValidateAcquisition(v *validator.Validator, book *Book) {
+	v.Check(validator.PermittedValue(book.Ownership, Ownerships...), "ownership", "must be one of wishlist, owned, sold or given_away")
+	v.Check(book.Format == "" || validator.PermittedValue(book.Format, BookFormats...), "format", "must be one of hardcover, paperback, ebook or audiobook")
+	v.Check(len(book.Vendor) <= 255, "vendor", "must not be more than 255 bytes long")
+
+	if book.PurchaseDate != "" {
+		date, err := time.Parse(dateFinishedLayout, book.PurchaseDate)
+		v.Check(err == nil, "purchase_date", "must be a date in the format YYYY-MM-DD")
+		v.Check(err != nil || !date.After(time.Now()), "purchase_date", "must not be in the future")
+		v.Check(book.Ownership != OwnershipWishlist, "purchase_date", "a wishlisted book hasn't been bought yet")
+	}
+
+	if book.Price != nil {
+		v.Check(*book.Price >= 0, "price", "must not be negative")
+		v.Check(*book.Price < 100000000, "price", "must be less than 100000000")
+		v.Check(book.Currency != "", "currency", "must be provided with a price")
+	}
+	v.Check(book.Currency == "" || validator.Matches(book.Currency, currencyRX), "currency", "must be a three letter currency code such as EUR")
+}
+
+// Reading statuses, matching the 1-based index of the status ENUM.
+const (
+	StatusNotRead    = 1
+	StatusInProgress = 2
+	StatusRead       = 3
+)
+
+// ValidateStatus checks a reading status that was supplied. Status 0 is only ever
+// "not supplied" and is never written.
+func ValidateStatus(v *validator.Validator, status int) {
+	v.Check(status >= StatusNotRead && status <= StatusRead, "status", "must be between 1 and 3")
+}
+
+// StatusNames are the names of the reading statuses, in status order.
+var StatusNames = []string{"Not Read", "In progress", "Read"}
+
+// ParseStatus reads a status name, ignoring case, or its number. An empty name is
+// status 0, which leaves the status unset.
+func ParseStatus(name string) (int, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, true
+	}
+
+	for i, statusName := range StatusNames {
+		if strings.EqualFold(name, statusName) || name == strconv.Itoa(i+1) {
+			return i + 1, true
+		}
+	}
+
+	return 0, false
+}
+
+// bookColumns and bookJoins are shared by the book queries. The reading status
+// lives in cg_user_books, books the user hasn't touched yet are "Not Read". The
+// ratings are averaged over every review of the book. A book has at most one loan
+// that hasn't been returned, which gives the current borrower.
+const (
+	bookColumns = `b.id, b.library_id, b.work_id, b.title, COALESCE(ub.status, 'Not Read'), COALESCE(ub.status + 0, 1), b.subtitle, b.description, b.page_count, b.image, b.published_date, b.isbn, COALESCE(ub.status_id, 0), b.created_at, b.updated_at, COALESCE(rv.average_rating, 0), COALESCE(rv.rating_count, 0), ln.id IS NOT NULL, COALESCE(ln.borrower_name, ''), b.ownership, COALESCE(b.format, ''), COALESCE(DATE_FORMAT(b.purchase_date, '%Y-%m-%d'), ''), b.price, COALESCE(b.currency, ''), COALESCE(b.vendor, '')`
+	bookJoins   = ` LEFT JOIN cg_user_books ub ON ub.book_id = b.id AND ub.user_id = ?` +
+		` LEFT JOIN (SELECT book_id, ROUND(AVG(rating), 2) AS average_rating, COUNT(*) AS rating_count FROM cg_reviews GROUP BY book_id) rv ON rv.book_id = b.id` +
+		` LEFT JOIN cg_loans ln ON ln.book_id = b.id AND ln.returned_at IS NULL`
+)
+
+// scanDest returns the destinations for the columns in bookColumns, in order.
+func (bk *Book) scanDest() []any {
+	return []any{&bk.ID, &bk.LibraryID, &bk.WorkID, &bk.Title, &bk.StatusName, &bk.Status, &bk.Subtitle, &bk.Description, &bk.PageCount, &bk.Image, &bk.PublishedDate, &bk.ISBN, &bk.StatusID, &bk.CreatedAt, &bk.UpdatedAt, &bk.AverageRating, &bk.RatingCount, &bk.LentOut, &bk.Borrower, &bk.Ownership, &bk.Format, &bk.PurchaseDate, &bk.Price, &bk.Currency, &bk.Vendor}
+}
+
+// HidePrivate clears what only the library's own users may see, the borrower and
+// what was paid for the book, before it is shown to anyone with a share link.
+func (bk *Book) HidePrivate() {
+	bk.Borrower = ""
+	bk.PurchaseDate = ""
+	bk.Price = nil
+	bk.Currency = ""
+	bk.Vendor = ""
+}
+
+// bookSortColumns maps the sort safelist onto the expressions used in ORDER BY.
+var bookSortColumns = map[string]string{
+	"status": "COALESCE(ub.status + 0, 1)",
+	"rating": "COALESCE(rv.average_rating, 0)",
+}
+
+// bookOrderBy returns the ORDER BY expressions for the filters' sort, ties are
+// broken by id.
+func bookOrderBy(filters Filters) string {
+	sortColumn, ok := bookSortColumns[filters.sortColumn()]
+	if !ok {
+		sortColumn = "b." + filters.sortColumn()
+	}
+
+	return fmt.Sprintf(`%s %s, b.id ASC`, sortColumn, filters.sortDirection())
+}
+
 type BookModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
-// Insert new book and returns new book id
+// Insert new book into book.LibraryID as an edition of book.WorkID and returns new book id
 func (b *BookModel) Insert(book *Book) (int, error) {
 	query := `
-    INSERT INTO cg_books(title,subtitle,description,page_count,image,published_date,isbn,status,status_id,created_at,updated_at) VALUES (?,?,?,?,?,?,?,?,?, UTC_TIMESTAMP(), UTC_TIMESTAMP())
+    INSERT INTO cg_books(library_id,work_id,title,subtitle,description,page_count,image,published_date,isbn,ownership,format,purchase_date,price,currency,vendor,created_at,updated_at) VALUES (?,?,?,?,?,?,?,?,?,?,NULLIF(?, ''),?,?,NULLIF(?, ''),NULLIF(TRIM(?), ''), UTC_TIMESTAMP(), UTC_TIMESTAMP())
   `
-	args := []any{book.Title, book.Subtitle, book.Description, book.PageCount, book.Image, book.PublishedDate, book.ISBN, book.Status, book.StatusID}
+	args := []any{book.LibraryID, book.WorkID, book.Title, book.Subtitle, book.Description, book.PageCount, book.Image, book.PublishedDate, book.ISBN, book.Ownership, book.Format, nullableDate(book.PurchaseDate), book.Price, book.Currency, book.Vendor}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
@@ -76,38 +213,166 @@ func (b *BookModel) Insert(book *Book) (int, error) {
 	return int(id), nil
 }
 
-func (b *BookModel) GetBooks(qs url.Values) ([]*Book, error) {
-	query := `SELECT DISTINCT(b.id), b.title, b.status, b.subtitle, b.description, b.page_count, b.image, b.published_date, b.isbn, b.status_id, b.created_at, b.updated_at FROM cg_books b`
-
-	authors := strings.Split(qs.Get("authors"), ",")
-	categories := strings.Split(qs.Get("categories"), ",")
+// GetBooks returns the books in the user's library, filtered by the authors,
+// categories and publishers ids in the query string. A category also matches the
+// books in all of its subcategories. Books can also be filtered by tag names,
+// matching any of the tags or, with tags_match=all, all of them, by ownership
+// and format, by vendor and by a purchase date range. They are sorted the same
+// way as GetFilteredBooks, by the filters' sort.
+func (b *BookModel) GetBooks(qs url.Values, filters Filters, user *User) ([]*Book, error) {
+	query := `SELECT ` + bookColumns + ` FROM cg_books b` + bookJoins
 
-	var authorIds []string
-	var categoryIds []string
+	conditions := []string{`b.library_id = ?`}
+	args := []any{user.ID, user.LibraryID}
 
 	if qs.Get("authors") != "" {
-		query += ` LEFT JOIN cg_book_authors ba ON ba.book_id = b.id`
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM cg_work_authors wa WHERE wa.work_id = b.work_id AND wa.role = 'author' AND wa.author_id IN (`+placeholders(qs.Get("authors"), &args)+`))`)
 	}
 	if qs.Get("categories") != "" {
-		query += ` LEFT JOIN cg_book_categories bc ON bc.book_id = b.id`
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM cg_work_categories wc WHERE wc.work_id = b.work_id AND wc.category_id IN (`+categoryWithDescendants(placeholders(qs.Get("categories"), &args))+`))`)
+	}
+	if qs.Get("publishers") != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM cg_book_publisher bp WHERE bp.book_id = b.id AND bp.publisher_id IN (`+placeholders(qs.Get("publishers"), &args)+`))`)
+	}
+	if qs.Get("series") != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM cg_book_series bs WHERE bs.book_id = b.id AND bs.series_id IN (`+placeholders(qs.Get("series"), &args)+`))`)
+	}
+	if qs.Get("tags") != "" {
+		tags := NormalizeTags(strings.Split(qs.Get("tags"), ","))
+		conditions = append(conditions, tagCondition(tags, qs.Get("tags_match") == "all", &args))
+	}
+	if qs.Get("ownership") != "" {
+		conditions = append(conditions, `b.ownership IN (`+placeholders(qs.Get("ownership"), &args)+`)`)
+	}
+	if qs.Get("format") != "" {
+		conditions = append(conditions, `b.format IN (`+placeholders(qs.Get("format"), &args)+`)`)
+	}
+	if qs.Get("vendor") != "" {
+		conditions = append(conditions, `LOWER(b.vendor) = LOWER(?)`)
+		args = append(args, strings.TrimSpace(qs.Get("vendor")))
+	}
+	if qs.Get("purchased_from") != "" {
+		conditions = append(conditions, `b.purchase_date >= ?`)
+		args = append(args, qs.Get("purchased_from"))
+	}
+	if qs.Get("purchased_to") != "" {
+		conditions = append(conditions, `b.purchase_date <= ?`)
+		args = append(args, qs.Get("purchased_to"))
 	}
 
-	if qs.Get("authors") != "" {
-		authorIds = append(authorIds, authors...)
-		query += ` WHERE ba.author_id IN ` + `(` + strings.Join(authorIds, ",") + `)`
+	query += ` WHERE ` + strings.Join(conditions, ` AND `)
+
+	query += ` ORDER BY ` + bookOrderBy(filters)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := b.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
-	if qs.Get("categories") != "" {
-		categoryIds = append(categoryIds, categories...)
-		query += ` AND bc.category_id IN ` + `(` + strings.Join(categoryIds, ",") + `)`
+
+	defer results.Close()
+
+	books := []*Book{}
+
+	for results.Next() {
+		bk := &Book{}
+
+		err := results.Scan(bk.scanDest()...)
+		if err != nil {
+			return nil, err
+		}
+
+		books = append(books, bk)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, err
 	}
 
-	query += " ORDER BY b.title ASC"
+	return books, nil
+}
+
+// GetFilteredBooks returns a page of books in the user's library matching the title
+// substring, author and category ids and the user's reading status, together with
+// the pagination metadata. Empty arguments (and a status of 0) don't filter anything.
+func (b *BookModel) GetFilteredBooks(title string, authors []string, categories []string, status int, filters Filters, user *User) ([]*Book, Metadata, error) {
+	query := `SELECT COUNT(*) OVER(), ` + bookColumns + ` FROM cg_books b` + bookJoins
+
+	conditions := []string{`b.library_id = ?`}
+	args := []any{user.ID, user.LibraryID}
+
+	if title != "" {
+		// % and _ in the title are matched literally
+		conditions = append(conditions, `LOWER(b.title) LIKE LOWER(?)`)
+		args = append(args, "%"+strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(title)+"%")
+	}
+	if len(authors) > 0 {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM cg_work_authors wa WHERE wa.work_id = b.work_id AND wa.role = 'author' AND wa.author_id IN (`+placeholders(strings.Join(authors, ","), &args)+`))`)
+	}
+	if len(categories) > 0 {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM cg_work_categories wc WHERE wc.work_id = b.work_id AND wc.category_id IN (`+categoryWithDescendants(placeholders(strings.Join(categories, ","), &args))+`))`)
+	}
+	if status > 0 {
+		// status is an ENUM, adding 0 compares against its 1-based index
+		conditions = append(conditions, `COALESCE(ub.status + 0, 1) = ?`)
+		args = append(args, status)
+	}
+
+	query += ` WHERE ` + strings.Join(conditions, ` AND `)
+
+	query += ` ORDER BY ` + bookOrderBy(filters) + ` LIMIT ? OFFSET ?`
+	args = append(args, filters.limit(), filters.offset())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
 	defer cancel()
 
-	results, err := b.DB.QueryContext(ctx, query)
+	results, err := b.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer results.Close()
+
+	totalRecords := 0
+	books := []*Book{}
+
+	for results.Next() {
+		bk := &Book{}
+
+		err := results.Scan(append([]any{&totalRecords}, bk.scanDest()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		books = append(books, bk)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return books, metadata, nil
+}
+
+// GetSeriesBooks returns the books of a series in the user's library in reading
+// order.
+func (b *BookModel) GetSeriesBooks(seriesID int64, user *User) ([]*Book, error) {
+	query := `SELECT ` + bookColumns + `, bs.position FROM cg_books b
+						INNER JOIN cg_book_series bs ON bs.book_id = b.id` + bookJoins + `
+						WHERE bs.series_id = ? AND b.library_id = ?
+						ORDER BY bs.position, b.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := b.DB.QueryContext(ctx, query, user.ID, seriesID, user.LibraryID)
 	if err != nil {
 		return nil, err
 	}
@@ -118,11 +383,13 @@ func (b *BookModel) GetBooks(qs url.Values) ([]*Book, error) {
 
 	for results.Next() {
 		bk := &Book{}
+		var position float64
 
-		err := results.Scan(&bk.ID, &bk.Title, &bk.StatusName, &bk.Subtitle, &bk.Description, &bk.PageCount, &bk.Image, &bk.PublishedDate, &bk.ISBN, &bk.StatusID, &bk.CreatedAt, &bk.UpdatedAt)
+		err := results.Scan(append(bk.scanDest(), &position)...)
 		if err != nil {
 			return nil, err
 		}
+		bk.SeriesPosition = &position
 
 		books = append(books, bk)
 	}
@@ -134,14 +401,36 @@ func (b *BookModel) GetBooks(qs url.Values) ([]*Book, error) {
 	return books, nil
 }
 
-func (b *BookModel) GetFilteredBooks(title string, authors []string, categories []string, filters Filters) ([]*Book, error) {
-	query := `SELECT * FROM cg_books`
+// NextUnreadInSeries returns the first book of the series, in reading order, that
+// the user hasn't marked as Read. ErrRecordNotFound means the series is finished.
+func (b *BookModel) NextUnreadInSeries(seriesID int64, user *User) (*Book, error) {
+	books, err := b.GetSeriesBooks(seriesID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, book := range books {
+		if book.Status != StatusRead {
+			return book, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+// GetShelfBooks returns the books on the shelf that are in the user's library, in
+// shelf order.
+func (b *BookModel) GetShelfBooks(shelfID int64, user *User) ([]*Book, error) {
+	query := `SELECT ` + bookColumns + `, sb.position FROM cg_books b
+						INNER JOIN cg_shelf_books sb ON sb.book_id = b.id` + bookJoins + `
+						WHERE sb.shelf_id = ? AND b.library_id = ?
+						ORDER BY sb.position, sb.id`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
 	defer cancel()
 
-	results, err := b.DB.QueryContext(ctx, query)
+	results, err := b.DB.QueryContext(ctx, query, user.ID, shelfID, user.LibraryID)
 	if err != nil {
 		return nil, err
 	}
@@ -152,11 +441,13 @@ func (b *BookModel) GetFilteredBooks(title string, authors []string, categories
 
 	for results.Next() {
 		bk := &Book{}
+		var position int
 
-		err := results.Scan(&bk.ID, &bk.Title, &bk.StatusName, &bk.StatusID, &bk.CreatedAt, &bk.UpdatedAt)
+		err := results.Scan(append(bk.scanDest(), &position)...)
 		if err != nil {
 			return nil, err
 		}
+		bk.ShelfPosition = &position
 
 		books = append(books, bk)
 	}
@@ -168,18 +459,103 @@ func (b *BookModel) GetFilteredBooks(title string, authors []string, categories
 	return books, nil
 }
 
-func (b *BookModel) DeleteBook(id int64) error {
+// InLibrary reports whether every book in ids belongs to the library.
+func (b *BookModel) InLibrary(ids []int64, libraryID int64) (bool, error) {
+	if len(ids) == 0 {
+		return true, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT COUNT(DISTINCT id) FROM cg_books WHERE library_id = ? AND id IN (` + in + `)`
+
+	var count int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := b.DB.QueryRowContext(ctx, query, append([]any{libraryID}, args...)...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count == len(ids), nil
+}
+
+// ISBNInLibrary reports whether the library already has an edition with the ISBN.
+func (b *BookModel) ISBNInLibrary(isbn string, libraryID int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM cg_books WHERE isbn = ? AND library_id = ?)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	var exists bool
+
+	err := b.DB.QueryRowContext(ctx, query, isbn, libraryID).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// GetAuthorBooks returns the books in the user's library the author contributed
+// to, grouped by role and ordered by title.
+func (b *BookModel) GetAuthorBooks(authorID int64, user *User) (map[string][]*Book, error) {
+	query := `SELECT wa.role, ` + bookColumns + ` FROM cg_books b
+						INNER JOIN cg_work_authors wa ON wa.work_id = b.work_id` + bookJoins + `
+						WHERE wa.author_id = ? AND b.library_id = ?
+						ORDER BY wa.role, b.title, b.id`
+
+	args := []any{user.ID, authorID, user.LibraryID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := b.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer results.Close()
+
+	books := map[string][]*Book{}
+
+	for results.Next() {
+		bk := &Book{}
+		var role string
+
+		err := results.Scan(append([]any{&role}, bk.scanDest()...)...)
+		if err != nil {
+			return nil, err
+		}
+
+		books[role] = append(books[role], bk)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// DeleteBook deletes the book if it belongs to the library
+func (b *BookModel) DeleteBook(id int64, libraryID int64) error {
 
 	if id < 0 {
 		return ErrRecordNotFound
 	}
-	query := `DELETE FROM cg_books WHERE id = ?`
+	query := `DELETE FROM cg_books WHERE id = ? AND library_id = ?`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
 	defer cancel()
 
-	result, err := b.DB.ExecContext(ctx, query, id)
+	result, err := b.DB.ExecContext(ctx, query, id, libraryID)
 	if err != nil {
 		return err
 	}
@@ -197,11 +573,13 @@ func (b *BookModel) DeleteBook(id int64) error {
 
 }
 
-func (b *BookModel) GetBook(id int64) (*Book, error) {
+// GetBook returns the book with the user's reading status. Books from other
+// libraries are reported as ErrRecordNotFound.
+func (b *BookModel) GetBook(id int64, user *User) (*Book, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
-	query := `SELECT * FROM cg_books WHERE id = ?`
+	query := `SELECT ` + bookColumns + ` FROM cg_books b` + bookJoins + ` WHERE b.id = ? AND b.library_id = ?`
 
 	var book Book
 
@@ -209,7 +587,7 @@ func (b *BookModel) GetBook(id int64) (*Book, error) {
 
 	defer cancel()
 
-	err := b.DB.QueryRowContext(ctx, query, id).Scan(&book.ID, &book.Title, &book.StatusName, &book.StatusID, &book.CreatedAt, &book.UpdatedAt)
+	err := b.DB.QueryRowContext(ctx, query, user.ID, id, user.LibraryID).Scan(book.scanDest()...)
 
 	if err != nil {
 		switch {
@@ -224,16 +602,179 @@ func (b *BookModel) GetBook(id int64) (*Book, error) {
 
 }
 
+// GetWorkEditions returns the editions of a work in the user's library, oldest
+// first.
+func (b *BookModel) GetWorkEditions(workID int64, user *User) ([]*Book, error) {
+	query := `SELECT ` + bookColumns + ` FROM cg_books b` + bookJoins + `
+						WHERE b.work_id = ? AND b.library_id = ?
+						ORDER BY b.created_at, b.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := b.DB.QueryContext(ctx, query, user.ID, workID, user.LibraryID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer results.Close()
+
+	books := []*Book{}
+
+	for results.Next() {
+		bk := &Book{}
+
+		err := results.Scan(bk.scanDest()...)
+		if err != nil {
+			return nil, err
+		}
+
+		books = append(books, bk)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
 func (b *BookModel) UpdateBook(book *Book) error {
-	query := `UPDATE cg_books SET title = ?, status = ?, status_id = ?, updated_at = UTC_TIMESTAMP() WHERE id = ?`
+	query := `UPDATE cg_books SET title = ?, work_id = ?, ownership = ?, format = NULLIF(?, ''), purchase_date = ?, price = ?, currency = NULLIF(?, ''), vendor = NULLIF(TRIM(?), ''), updated_at = UTC_TIMESTAMP() WHERE id = ? AND library_id = ?`
+
+	args := []any{book.Title, book.WorkID, book.Ownership, book.Format, nullableDate(book.PurchaseDate), book.Price, book.Currency, book.Vendor, book.ID, book.LibraryID}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
 	defer cancel()
 
-	_, err := b.DB.ExecContext(ctx, query, book.Title, book.Status, book.StatusID, book.ID)
+	_, err := b.DB.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// SetStatus records the user's reading status for the book. When the status
+// actually changes the change is also added to cg_status_history. Moving the
+// book to Read stamps finished_at, which is kept while the book stays Read and
+// cleared when it moves back, so reading goals count each book once.
+func (b *BookModel) SetStatus(userID int64, book *Book) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	current := StatusNotRead
+
+	err := b.DB.QueryRowContext(ctx, `SELECT status + 0 FROM cg_user_books WHERE user_id = ? AND book_id = ?`, userID, book.ID).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// finished_at is assigned before status so it still sees the old status
+	query := `INSERT INTO cg_user_books (user_id, book_id, status, status_id, finished_at, created_at, updated_at) VALUES (?, ?, ?, ?, IF(?, UTC_TIMESTAMP(), NULL), UTC_TIMESTAMP(), UTC_TIMESTAMP())
+						ON DUPLICATE KEY UPDATE finished_at = IF(VALUES(status) = 'Read', IF(status = 'Read', COALESCE(finished_at, UTC_TIMESTAMP()), UTC_TIMESTAMP()), NULL), status = VALUES(status), status_id = VALUES(status_id), updated_at = UTC_TIMESTAMP()`
+
+	_, err = b.DB.ExecContext(ctx, query, userID, book.ID, book.Status, book.StatusID, book.Status == StatusRead)
+	if err != nil {
+		return err
+	}
+
+	if book.Status == current {
+		return nil
+	}
+
+	query = `INSERT INTO cg_status_history (user_id, book_id, status, changed_at) VALUES (?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err = b.DB.ExecContext(ctx, query, userID, book.ID, book.Status)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Spend is the money spent on books in one currency over a month ("2024-03")
+// or a year ("2024").
+type Spend struct {
+	Period   string  `json:"period"`
+	Currency string  `json:"currency"`
+	Total    float64 `json:"total"`
+	Books    int     `json:"books"`
+}
+
+// GetSpend sums the prices of the books bought for the library per month, or per
+// year with byYear, and currency, newest period first. Wishlisted books and books
+// without a purchase date or price aren't counted.
+func (b *BookModel) GetSpend(libraryID int64, byYear bool) ([]*Spend, error) {
+	period := `DATE_FORMAT(purchase_date, '%Y-%m')`
+	if byYear {
+		period = `DATE_FORMAT(purchase_date, '%Y')`
+	}
+
+	query := `SELECT ` + period + ` AS period, currency, SUM(price), COUNT(*) FROM cg_books
+						WHERE library_id = ? AND ownership <> 'wishlist' AND purchase_date IS NOT NULL AND price IS NOT NULL AND currency IS NOT NULL
+						GROUP BY period, currency
+						ORDER BY period DESC, currency`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := b.DB.QueryContext(ctx, query, libraryID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	spend := []*Spend{}
+
+	for rows.Next() {
+		var s Spend
+
+		err := rows.Scan(&s.Period, &s.Currency, &s.Total, &s.Books)
+		if err != nil {
+			return nil, err
+		}
+
+		spend = append(spend, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return spend, nil
+}
+
+// SetFinishedAt backdates when the user finished a book they have marked Read, for
+// reading history brought in from elsewhere.
+func (b *BookModel) SetFinishedAt(userID int64, bookID int64, finishedAt time.Time) error {
+	query := `UPDATE cg_user_books SET finished_at = ? WHERE user_id = ? AND book_id = ? AND status = 'Read'`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := b.DB.ExecContext(ctx, query, finishedAt.UTC(), userID, bookID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// placeholders turns a comma separated list of ids from the query string into
+// a "?,?,?" fragment, appending the ids to args.
+func placeholders(csv string, args *[]any) string {
+	ids := strings.Split(csv, ",")
+	marks := make([]string, len(ids))
+
+	for i, id := range ids {
+		marks[i] = "?"
+		*args = append(*args, strings.TrimSpace(id))
+	}
+
+	return strings.Join(marks, ",")
+}
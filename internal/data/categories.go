@@ -9,38 +9,91 @@ import (
 	"github.com/tklara86/book_catalogue/internal/validator"
 )
 
+// Category is a node in the category tree. Root categories have no parent.
+// BooksInCategory counts the books filed directly under the category,
+// BooksInCategoryTotal also counts the books in all of its descendants.
 type Category struct {
-	ID              int64     `json:"id"`
-	Name            string    `json:"name"`
-	BooksInCategory int       `json:"books_in_category,omitempty"`
-	DateAdded       string    `json:"date_added"`
-	DateUpdated     string    `json:"date_updated"`
-	CreatedAt       time.Time `json:"-"`
-	UpdatedAt       time.Time `json:"-"`
+	ID                   int64       `json:"id"`
+	ParentID             *int64      `json:"parent_id"`
+	Name                 string      `json:"name"`
+	BooksInCategory      int         `json:"books_in_category,omitempty"`
+	BooksInCategoryTotal int         `json:"books_in_category_total,omitempty"`
+	Children             []*Category `json:"children,omitempty"`
+	DateAdded            string      `json:"date_added"`
+	DateUpdated          string      `json:"date_updated"`
+	CreatedAt            time.Time   `json:"-"`
+	UpdatedAt            time.Time   `json:"-"`
 }
 
-type BookCategory struct {
+// WorkCategory links a category to a work, and so to every edition of it.
+type WorkCategory struct {
 	ID         int64     `json:"id"`
-	BookId     int64     `json:"book_id"`
+	WorkId     int64     `json:"work_id"`
 	CategoryId int64     `json:"category_id"`
 	CreatedAt  time.Time `json:"created_at,omitempty"`
 	UpdatedAt  time.Time `json:"updated_at,omitempty"`
 }
 
 type CategoryModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
-func ValidateCategory(v *validator.Validator, category *Category) {
+// ValidateCategory checks the category. parentAncestry is the id of the new parent
+// followed by the ids of all of its ancestors (see GetCategoryAncestry); a category
+// can't be moved under itself or one of its own descendants.
+func ValidateCategory(v *validator.Validator, category *Category, parentAncestry []int64) {
 
 	v.Check(category.Name != "", "name", "Name cannot be empty")
 
+	if category.ParentID != nil {
+		v.Check(len(parentAncestry) > 0, "parent_id", "must be an existing category")
+
+		for _, id := range parentAncestry {
+			if id == category.ID {
+				v.AddError("parent_id", "a category cannot be moved under itself or one of its subcategories")
+				break
+			}
+		}
+	}
+
+}
+
+// categoryWithDescendants returns a subquery selecting the ids of the categories
+// in the "in" placeholder list together with the ids of all their descendants.
+func categoryWithDescendants(in string) string {
+	return `WITH RECURSIVE tree AS (
+						SELECT id FROM cg_categories WHERE id IN (` + in + `)
+						UNION
+						SELECT c.id FROM cg_categories c INNER JOIN tree t ON c.parent_id = t.id
+					) SELECT id FROM tree`
+}
+
+// CategoryTree nests the categories under their parents and returns the roots.
+// Categories must contain the whole tree.
+func CategoryTree(categories []*Category) []*Category {
+	byId := make(map[int64]*Category, len(categories))
+	for _, cat := range categories {
+		byId[cat.ID] = cat
+	}
+
+	roots := []*Category{}
+	for _, cat := range categories {
+		if cat.ParentID != nil {
+			if parent, ok := byId[*cat.ParentID]; ok {
+				parent.Children = append(parent.Children, cat)
+				continue
+			}
+		}
+		roots = append(roots, cat)
+	}
+
+	return roots
 }
 
 func (c *CategoryModel) Insert(category *Category) (int, error) {
-	query := `INSERT INTO cg_categories (name,created_at,updated_at) VALUES (TRIM(?), UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+	query := `INSERT INTO cg_categories (parent_id,name,created_at,updated_at) VALUES (?, TRIM(?), UTC_TIMESTAMP(), UTC_TIMESTAMP())`
 
-	args := []any{category.Name}
+	args := []any{category.ParentID, category.Name}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
@@ -48,7 +101,7 @@ func (c *CategoryModel) Insert(category *Category) (int, error) {
 
 	result, err := c.DB.ExecContext(ctx, query, args...)
 	if err != nil {
-		return 0, nil
+		return 0, err
 	}
 
 	id, err := result.LastInsertId()
@@ -59,15 +112,19 @@ func (c *CategoryModel) Insert(category *Category) (int, error) {
 	return int(id), nil
 }
 
-func (c *CategoryModel) InsertBookCategories(bc []BookCategory) (int, error) {
+func (c *CategoryModel) InsertWorkCategories(wc []WorkCategory) (int, error) {
+	if len(wc) == 0 {
+		return 0, nil
+	}
+
 	query := `
-	INSERT INTO cg_book_categories (book_id, category_id, created_at, updated_at)
+	INSERT INTO cg_work_categories (work_id, category_id, created_at, updated_at)
 	VALUES `
 
 	args := []any{}
 
-	for _, v := range bc {
-		args = append(args, v.BookId, v.CategoryId)
+	for _, v := range wc {
+		args = append(args, v.WorkId, v.CategoryId)
 		numFields := 1
 
 		for j := 0; j < numFields; j++ {
@@ -95,7 +152,7 @@ func (c *CategoryModel) InsertBookCategories(bc []BookCategory) (int, error) {
 }
 
 func (c *CategoryModel) GetCategories() ([]*Category, error) {
-	query := `SELECT * FROM cg_categories`
+	query := `SELECT id, parent_id, name, created_at, updated_at FROM cg_categories ORDER BY name`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
@@ -113,7 +170,7 @@ func (c *CategoryModel) GetCategories() ([]*Category, error) {
 	for rows.Next() {
 		cat := &Category{}
 
-		err = rows.Scan(&cat.ID, &cat.Name, &cat.CreatedAt, &cat.UpdatedAt)
+		err = rows.Scan(&cat.ID, &cat.ParentID, &cat.Name, &cat.CreatedAt, &cat.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -127,13 +184,56 @@ func (c *CategoryModel) GetCategories() ([]*Category, error) {
 
 }
 
+// GetCategoriesPage returns a page of the categories with books in the library,
+// ordered by name.
+func (c *CategoryModel) GetCategoriesPage(libraryID int64, filters Filters) ([]*Category, Metadata, error) {
+	query := `SELECT COUNT(*) OVER(), c.id, c.parent_id, c.name, c.created_at, c.updated_at FROM cg_categories c
+						INNER JOIN cg_work_categories wc ON wc.category_id = c.id
+						INNER JOIN cg_books b ON b.work_id = wc.work_id
+						WHERE b.library_id = ?
+						GROUP BY c.id
+						ORDER BY c.name, c.id
+						LIMIT ? OFFSET ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := c.DB.QueryContext(ctx, query, libraryID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	categories := []*Category{}
+
+	for rows.Next() {
+		cat := &Category{}
+
+		err = rows.Scan(&totalRecords, &cat.ID, &cat.ParentID, &cat.Name, &cat.CreatedAt, &cat.UpdatedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		categories = append(categories, cat)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return categories, metadata, nil
+}
+
 func (c *CategoryModel) GetCategory(id int64) (*Category, error) {
 
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	query := `SELECT * FROM cg_categories WHERE id = ?`
+	query := `SELECT id, parent_id, name, created_at, updated_at FROM cg_categories WHERE id = ?`
 
 	var category Category
 
@@ -141,7 +241,7 @@ func (c *CategoryModel) GetCategory(id int64) (*Category, error) {
 
 	defer cancel()
 
-	err := c.DB.QueryRowContext(ctx, query, id).Scan(&category.ID, &category.Name, &category.CreatedAt, &category.UpdatedAt)
+	err := c.DB.QueryRowContext(ctx, query, id).Scan(&category.ID, &category.ParentID, &category.Name, &category.CreatedAt, &category.UpdatedAt)
 
 	if err != nil {
 		switch {
@@ -156,10 +256,34 @@ func (c *CategoryModel) GetCategory(id int64) (*Category, error) {
 
 }
 
-func (c *CategoryModel) GetBooksInCategory(id int64) (int, error) {
+// GetCategoryIDByName finds a category by name, ignoring case, wherever it is in
+// the tree. When several categories share the name the oldest one is returned.
+func (c *CategoryModel) GetCategoryIDByName(name string) (int64, error) {
+	query := `SELECT id FROM cg_categories WHERE LOWER(name) = LOWER(TRIM(?)) ORDER BY id LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	var id int64
+
+	err := c.DB.QueryRowContext(ctx, query, name).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+func (c *CategoryModel) GetBooksInCategory(id int64, libraryID int64) (int, error) {
 	query := `SELECT COUNT(b.id) FROM cg_books b
-						LEFT JOIN cg_book_categories bc ON bc.book_id = b.id
-						WHERE bc.category_id = ?`
+						INNER JOIN cg_work_categories wc ON wc.work_id = b.work_id
+						WHERE wc.category_id = ? AND b.library_id = ?`
 
 	var bookCategoryNumber int
 
@@ -167,7 +291,7 @@ func (c *CategoryModel) GetBooksInCategory(id int64) (int, error) {
 
 	defer cancel()
 
-	err := c.DB.QueryRowContext(ctx, query, id).Scan(&bookCategoryNumber)
+	err := c.DB.QueryRowContext(ctx, query, id, libraryID).Scan(&bookCategoryNumber)
 	if err != nil {
 		return 0, err
 	}
@@ -176,10 +300,72 @@ func (c *CategoryModel) GetBooksInCategory(id int64) (int, error) {
 
 }
 
+// GetBooksInCategoryTree counts the books in the library in the category and all
+// of its descendants.
+func (c *CategoryModel) GetBooksInCategoryTree(id int64, libraryID int64) (int, error) {
+	query := `SELECT COUNT(DISTINCT b.id) FROM cg_books b
+						INNER JOIN cg_work_categories wc ON wc.work_id = b.work_id
+						WHERE wc.category_id IN (` + categoryWithDescendants("?") + `) AND b.library_id = ?`
+
+	var bookCategoryNumber int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := c.DB.QueryRowContext(ctx, query, id, libraryID).Scan(&bookCategoryNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	return bookCategoryNumber, nil
+}
+
+// GetCategoryAncestry returns the id of the category followed by the ids of its
+// ancestors up to the root. It is empty when the category doesn't exist.
+func (c *CategoryModel) GetCategoryAncestry(id int64) ([]int64, error) {
+	query := `WITH RECURSIVE ancestry AS (
+						SELECT id, parent_id, 0 AS depth FROM cg_categories WHERE id = ?
+						UNION
+						SELECT c.id, c.parent_id, a.depth + 1 FROM cg_categories c INNER JOIN ancestry a ON c.id = a.parent_id
+						WHERE a.depth < 100
+					) SELECT id FROM ancestry ORDER BY depth`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := c.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var ancestorId int64
+
+		err := rows.Scan(&ancestorId)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, ancestorId)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
 func (c *CategoryModel) GetBookCategories(id int64) ([]*Category, error) {
-	query := `SELECT c.id, c.name, c.created_at, c.updated_at FROM cg_categories c
-						LEFT JOIN cg_book_categories bc ON bc.category_id = c.id
-						WHERE bc.book_id = ?`
+	query := `SELECT c.id, c.parent_id, c.name, c.created_at, c.updated_at FROM cg_categories c
+						INNER JOIN cg_work_categories wc ON wc.category_id = c.id
+						INNER JOIN cg_books b ON b.work_id = wc.work_id
+						WHERE b.id = ?`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
@@ -197,7 +383,7 @@ func (c *CategoryModel) GetBookCategories(id int64) ([]*Category, error) {
 	for results.Next() {
 		cat := &Category{}
 
-		err := results.Scan(&cat.ID, &cat.Name, &cat.CreatedAt, &cat.UpdatedAt)
+		err := results.Scan(&cat.ID, &cat.ParentID, &cat.Name, &cat.CreatedAt, &cat.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -208,14 +394,76 @@ func (c *CategoryModel) GetBookCategories(id int64) ([]*Category, error) {
 
 }
 
+// GetWorkCategories returns the categories of the work.
+func (c *CategoryModel) GetWorkCategories(workID int64) ([]*Category, error) {
+	query := `SELECT c.id, c.parent_id, c.name, c.created_at, c.updated_at FROM cg_categories c
+						INNER JOIN cg_work_categories wc ON wc.category_id = c.id
+						WHERE wc.work_id = ?
+						ORDER BY wc.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := c.DB.QueryContext(ctx, query, workID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer results.Close()
+
+	categories := []*Category{}
+
+	for results.Next() {
+		cat := &Category{}
+
+		err := results.Scan(&cat.ID, &cat.ParentID, &cat.Name, &cat.CreatedAt, &cat.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, cat)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// UsedOutsideLibrary reports whether any of the categories in ids is linked to books in
+// another library than libraryID.
+func (c *CategoryModel) UsedOutsideLibrary(ids []int64, libraryID int64) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT EXISTS (SELECT 1 FROM cg_work_categories wc INNER JOIN cg_books b ON b.work_id = wc.work_id WHERE wc.category_id IN (` + in + `) AND b.library_id <> ?)`
+
+	var used bool
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := c.DB.QueryRowContext(ctx, query, append(args, libraryID)...).Scan(&used)
+	if err != nil {
+		return false, err
+	}
+
+	return used, nil
+}
+
 func (c *CategoryModel) UpdateCategory(category *Category) error {
-	query := `UPDATE cg_categories SET name = ?, updated_at = UTC_TIMESTAMP() WHERE id = ?`
+	query := `UPDATE cg_categories SET parent_id = ?, name = ?, updated_at = UTC_TIMESTAMP() WHERE id = ?`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
 	defer cancel()
 
-	_, err := c.DB.ExecContext(ctx, query, category.Name, category.ID)
+	_, err := c.DB.ExecContext(ctx, query, category.ParentID, category.Name, category.ID)
 	if err != nil {
 		return err
 	}
@@ -250,12 +498,12 @@ func (c *CategoryModel) DeleteCategory(id int64) error {
 	return nil
 }
 
-func (c *CategoryModel) DeleteBookCategories(id int64) error {
+func (c *CategoryModel) DeleteWorkCategories(id int64) error {
 	if id < 0 {
 		return ErrRecordNotFound
 	}
 
-	query := `DELETE FROM cg_book_categories WHERE book_id = ?`
+	query := `DELETE FROM cg_work_categories WHERE work_id = ?`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
@@ -277,3 +525,133 @@ func (c *CategoryModel) DeleteBookCategories(id int64) error {
 
 	return nil
 }
+
+// GetCategoriesForBooks loads the categories of every book in ids with a single
+// query, keyed by book id.
+func (c *CategoryModel) GetCategoriesForBooks(ids []int64) (map[int64][]*Category, error) {
+	categories := make(map[int64][]*Category, len(ids))
+	if len(ids) == 0 {
+		return categories, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT b.id, c.id, c.parent_id, c.name, c.created_at, c.updated_at FROM cg_categories c
+						INNER JOIN cg_work_categories wc ON wc.category_id = c.id
+						INNER JOIN cg_books b ON b.work_id = wc.work_id
+						WHERE b.id IN (` + in + `)
+						ORDER BY b.id, wc.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := c.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var bookId int64
+		cat := &Category{}
+
+		err := results.Scan(&bookId, &cat.ID, &cat.ParentID, &cat.Name, &cat.CreatedAt, &cat.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		categories[bookId] = append(categories[bookId], cat)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// GetBooksInCategories counts the books in the library in every category in one
+// GROUP BY query, keyed by category id. Empty categories are missing from the map.
+func (c *CategoryModel) GetBooksInCategories(libraryID int64) (map[int64]int, error) {
+	query := `SELECT wc.category_id, COUNT(DISTINCT b.id) FROM cg_work_categories wc
+						INNER JOIN cg_books b ON b.work_id = wc.work_id
+						WHERE b.library_id = ?
+						GROUP BY wc.category_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := c.DB.QueryContext(ctx, query, libraryID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := map[int64]int{}
+
+	for rows.Next() {
+		var categoryId int64
+		var count int
+
+		err := rows.Scan(&categoryId, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[categoryId] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// GetBooksInCategoryTrees counts the books in the library in every category
+// together with the books in all of its descendants, keyed by category id.
+// Categories without any books in their subtree are missing from the map.
+func (c *CategoryModel) GetBooksInCategoryTrees(libraryID int64) (map[int64]int, error) {
+	query := `WITH RECURSIVE tree AS (
+						SELECT id AS root_id, id FROM cg_categories
+						UNION
+						SELECT t.root_id, c.id FROM cg_categories c INNER JOIN tree t ON c.parent_id = t.id
+					)
+					SELECT t.root_id, COUNT(DISTINCT b.id) FROM tree t
+						INNER JOIN cg_work_categories wc ON wc.category_id = t.id
+						INNER JOIN cg_books b ON b.work_id = wc.work_id
+						WHERE b.library_id = ?
+						GROUP BY t.root_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := c.DB.QueryContext(ctx, query, libraryID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := map[int64]int{}
+
+	for rows.Next() {
+		var categoryId int64
+		var count int
+
+		err := rows.Scan(&categoryId, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[categoryId] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
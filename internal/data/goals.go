@@ -0,0 +1,271 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// Goal units, a goal counts either the books or the pages read in the year.
+const (
+	GoalUnitBooks = "books"
+	GoalUnitPages = "pages"
+)
+
+type ReadingGoal struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"-"`
+	Year        int       `json:"year"`
+	Target      int       `json:"target"`
+	Unit        string    `json:"unit"`
+	DateAdded   string    `json:"date_added"`
+	DateUpdated string    `json:"date_updated"`
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+// MonthProgress is what was finished in one month of the goal's year.
+type MonthProgress struct {
+	Month int `json:"month"`
+	Books int `json:"books"`
+	Pages int `json:"pages"`
+}
+
+// GoalProgress compares what was read in the year against the goal. Expected is
+// how far along the goal should be by now for an even pace through the year and
+// Pace is the difference, negative when behind schedule.
+type GoalProgress struct {
+	Year            int              `json:"year"`
+	Target          int              `json:"target"`
+	Unit            string           `json:"unit"`
+	Completed       int              `json:"completed"`
+	Remaining       int              `json:"remaining"`
+	PercentComplete float64          `json:"percent_complete"`
+	Expected        int              `json:"expected"`
+	Pace            int              `json:"pace"`
+	Message         string           `json:"message"`
+	Months          []*MonthProgress `json:"months"`
+}
+
+func ValidateReadingGoal(v *validator.Validator, goal *ReadingGoal) {
+	v.Check(goal.Year >= 1900 && goal.Year <= 9999, "year", "must be a year between 1900 and 9999")
+	v.Check(goal.Target > 0, "target", "must be greater than zero")
+	v.Check(goal.Target <= 1_000_000, "target", "must not be more than 1000000")
+	v.Check(validator.PermittedValue(goal.Unit, GoalUnitBooks, GoalUnitPages), "unit", "must be books or pages")
+}
+
+// Progress works out how far the months got towards the goal at now. Before the
+// year starts nothing is expected yet and once it's over the whole target is.
+func (goal *ReadingGoal) Progress(months []*MonthProgress, now time.Time) GoalProgress {
+	progress := GoalProgress{
+		Year:   goal.Year,
+		Target: goal.Target,
+		Unit:   goal.Unit,
+		Months: months,
+	}
+
+	for _, month := range months {
+		if goal.Unit == GoalUnitPages {
+			progress.Completed += month.Pages
+		} else {
+			progress.Completed += month.Books
+		}
+	}
+
+	progress.Remaining = goal.Target - progress.Completed
+	if progress.Remaining < 0 {
+		progress.Remaining = 0
+	}
+
+	percent := float64(progress.Completed) / float64(goal.Target) * 100
+	progress.PercentComplete = math.Round(math.Min(percent, 100)*10) / 10
+
+	start := time.Date(goal.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+
+	switch {
+	case now.Before(start):
+		progress.Expected = 0
+	case !now.Before(end):
+		progress.Expected = goal.Target
+	default:
+		elapsed := now.Sub(start).Hours() / end.Sub(start).Hours()
+		progress.Expected = int(math.Floor(float64(goal.Target) * elapsed))
+	}
+
+	progress.Pace = progress.Completed - progress.Expected
+
+	switch {
+	case progress.Remaining == 0:
+		progress.Message = "you have reached your goal"
+	case progress.Pace < 0:
+		progress.Message = fmt.Sprintf("you are %s behind schedule", goalAmount(-progress.Pace, goal.Unit))
+	case progress.Pace > 0:
+		progress.Message = fmt.Sprintf("you are %s ahead of schedule", goalAmount(progress.Pace, goal.Unit))
+	default:
+		progress.Message = "you are on schedule"
+	}
+
+	return progress
+}
+
+// goalAmount formats n books or pages, "1 book", "3 books".
+func goalAmount(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit[:len(unit)-1])
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+type GoalModel struct {
+	DB DBTX
+}
+
+// SetGoal creates or replaces the user's goal for the year.
+func (gm *GoalModel) SetGoal(goal *ReadingGoal) error {
+	query := `INSERT INTO cg_reading_goals (user_id, year, target, unit, created_at, updated_at) VALUES (?, ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP())
+						ON DUPLICATE KEY UPDATE target = VALUES(target), unit = VALUES(unit), updated_at = UTC_TIMESTAMP()`
+
+	args := []any{goal.UserID, goal.Year, goal.Target, goal.Unit}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := gm.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetGoals returns the user's goals, latest year first.
+func (gm *GoalModel) GetGoals(userID int64) ([]*ReadingGoal, error) {
+	query := `SELECT id, user_id, year, target, unit, created_at, updated_at FROM cg_reading_goals WHERE user_id = ? ORDER BY year DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := gm.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	goals := []*ReadingGoal{}
+
+	for rows.Next() {
+		var goal ReadingGoal
+
+		err := rows.Scan(&goal.ID, &goal.UserID, &goal.Year, &goal.Target, &goal.Unit, &goal.CreatedAt, &goal.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, &goal)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return goals, nil
+}
+
+func (gm *GoalModel) GetGoal(userID int64, year int) (*ReadingGoal, error) {
+	query := `SELECT id, user_id, year, target, unit, created_at, updated_at FROM cg_reading_goals WHERE user_id = ? AND year = ?`
+
+	var goal ReadingGoal
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := gm.DB.QueryRowContext(ctx, query, userID, year).Scan(&goal.ID, &goal.UserID, &goal.Year, &goal.Target, &goal.Unit, &goal.CreatedAt, &goal.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &goal, nil
+}
+
+func (gm *GoalModel) DeleteGoal(userID int64, year int) error {
+	query := `DELETE FROM cg_reading_goals WHERE user_id = ? AND year = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := gm.DB.ExecContext(ctx, query, userID, year)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetMonthlyFinished counts the books of the library the user finished in each
+// month of the year, and their pages. Every month is returned, empty ones with
+// zeroes.
+func (gm *GoalModel) GetMonthlyFinished(userID int64, libraryID int64, year int) ([]*MonthProgress, error) {
+	query := `SELECT MONTH(ub.finished_at), COUNT(*), COALESCE(SUM(b.page_count), 0) FROM cg_user_books ub
+						INNER JOIN cg_books b ON b.id = ub.book_id
+						WHERE ub.user_id = ? AND b.library_id = ? AND ub.status = 'Read' AND ub.finished_at >= ? AND ub.finished_at < ?
+						GROUP BY MONTH(ub.finished_at)`
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := gm.DB.QueryContext(ctx, query, userID, libraryID, start, start.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	months := make([]*MonthProgress, 12)
+	for i := range months {
+		months[i] = &MonthProgress{Month: i + 1}
+	}
+
+	for rows.Next() {
+		var month, books, pages int
+
+		err := rows.Scan(&month, &books, &pages)
+		if err != nil {
+			return nil, err
+		}
+		months[month-1].Books = books
+		months[month-1].Pages = pages
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return months, nil
+}
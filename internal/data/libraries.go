@@ -0,0 +1,94 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// Library is a household catalogue. Books belong to a library and every user is a
+// member of exactly one.
+type Library struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Members     []*User   `json:"members,omitempty"`
+	DateAdded   string    `json:"date_added"`
+	DateUpdated string    `json:"date_updated"`
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+type LibraryModel struct {
+	DB DBTX
+}
+
+func ValidateLibrary(v *validator.Validator, library *Library) {
+
+	v.Check(library.Name != "", "name", "Name cannot be empty")
+	v.Check(len(library.Name) <= 255, "name", "Name must not be more than 255 characters long")
+
+}
+
+func (l *LibraryModel) Insert(library *Library) (int, error) {
+	query := `INSERT INTO cg_libraries (name,created_at,updated_at) VALUES (TRIM(?), UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := l.DB.ExecContext(ctx, query, library.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (l *LibraryModel) GetLibrary(id int64) (*Library, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, name, created_at, updated_at FROM cg_libraries WHERE id = ?`
+
+	var library Library
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := l.DB.QueryRowContext(ctx, query, id).Scan(&library.ID, &library.Name, &library.CreatedAt, &library.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &library, nil
+}
+
+func (l *LibraryModel) UpdateLibrary(library *Library) error {
+	query := `UPDATE cg_libraries SET name = TRIM(?), updated_at = UTC_TIMESTAMP() WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := l.DB.ExecContext(ctx, query, library.Name, library.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
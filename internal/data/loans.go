@@ -0,0 +1,222 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+var ErrBookLentOut = errors.New("book lent out")
+
+type Loan struct {
+	ID              int64     `json:"id"`
+	BookID          int64     `json:"book_id"`
+	BookTitle       string    `json:"book_title,omitempty"`
+	BorrowerName    string    `json:"borrower_name"`
+	BorrowerContact string    `json:"borrower_contact,omitempty"`
+	LentAt          string    `json:"lent_at"`
+	DueAt           string    `json:"due_at,omitempty"`
+	ReturnedAt      string    `json:"returned_at,omitempty"`
+	Overdue         bool      `json:"overdue"`
+	DateAdded       string    `json:"date_added"`
+	DateUpdated     string    `json:"date_updated"`
+	CreatedAt       time.Time `json:"-"`
+	UpdatedAt       time.Time `json:"-"`
+}
+
+func ValidateLoan(v *validator.Validator, loan *Loan) {
+	v.Check(loan.BorrowerName != "", "borrower_name", "must be provided")
+	v.Check(len(loan.BorrowerName) <= 255, "borrower_name", "must not be more than 255 bytes long")
+	v.Check(len(loan.BorrowerContact) <= 255, "borrower_contact", "must not be more than 255 bytes long")
+
+	lent, err := time.Parse(dateFinishedLayout, loan.LentAt)
+	v.Check(err == nil, "lent_at", "must be a date in the format YYYY-MM-DD")
+	v.Check(err != nil || !lent.After(time.Now()), "lent_at", "must not be in the future")
+
+	if loan.DueAt != "" {
+		due, dueErr := time.Parse(dateFinishedLayout, loan.DueAt)
+		v.Check(dueErr == nil, "due_at", "must be a date in the format YYYY-MM-DD")
+		v.Check(err != nil || dueErr != nil || !due.Before(lent), "due_at", "must not be before lent_at")
+	}
+
+	if loan.ReturnedAt != "" {
+		returned, returnedErr := time.Parse(dateFinishedLayout, loan.ReturnedAt)
+		v.Check(returnedErr == nil, "returned_at", "must be a date in the format YYYY-MM-DD")
+		v.Check(err != nil || returnedErr != nil || !returned.Before(lent), "returned_at", "must not be before lent_at")
+		v.Check(returnedErr != nil || !returned.After(time.Now()), "returned_at", "must not be in the future")
+	}
+}
+
+type LoanModel struct {
+	DB DBTX
+}
+
+// loanColumns is shared by the loan queries; a loan is overdue while it hasn't
+// been returned after its due date.
+const loanColumns = `l.id, l.book_id, b.title, l.borrower_name, COALESCE(l.borrower_contact, ''), l.lent_at, l.due_at, l.returned_at, l.returned_at IS NULL AND l.due_at < UTC_DATE(), l.created_at, l.updated_at`
+
+// Insert records a loan, returning ErrBookLentOut when the book already has a loan
+// that hasn't been returned.
+func (l *LoanModel) Insert(loan *Loan) (int, error) {
+	query := `INSERT INTO cg_loans (book_id, borrower_name, borrower_contact, lent_at, due_at, returned_at, created_at, updated_at) VALUES (?, TRIM(?), TRIM(?), ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	args := []any{loan.BookID, loan.BorrowerName, loan.BorrowerContact, loan.LentAt, nullableDate(loan.DueAt), nullableDate(loan.ReturnedAt)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := l.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			return 0, ErrBookLentOut
+		default:
+			return 0, err
+		}
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetBookLoans returns the loan history of the book, newest first.
+func (l *LoanModel) GetBookLoans(bookID int64) ([]*Loan, error) {
+	query := `SELECT ` + loanColumns + ` FROM cg_loans l
+						INNER JOIN cg_books b ON b.id = l.book_id
+						WHERE l.book_id = ?
+						ORDER BY l.lent_at DESC, l.id DESC`
+
+	return l.getLoans(query, bookID)
+}
+
+// GetOutstandingLoans returns the loans in the library that haven't been
+// returned, soonest due first. With overdueOnly only the loans past their due
+// date are returned.
+func (l *LoanModel) GetOutstandingLoans(libraryID int64, overdueOnly bool) ([]*Loan, error) {
+	query := `SELECT ` + loanColumns + ` FROM cg_loans l
+						INNER JOIN cg_books b ON b.id = l.book_id
+						WHERE b.library_id = ? AND l.returned_at IS NULL`
+
+	if overdueOnly {
+		query += ` AND l.due_at < UTC_DATE()`
+	}
+
+	query += ` ORDER BY l.due_at IS NULL, l.due_at, l.lent_at`
+
+	return l.getLoans(query, libraryID)
+}
+
+func (l *LoanModel) getLoans(query string, args ...any) ([]*Loan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := l.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	loans := []*Loan{}
+
+	for rows.Next() {
+		loan := &Loan{}
+
+		err := scanLoan(rows, loan)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, loan)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return loans, nil
+}
+
+func (l *LoanModel) GetLoan(id int64, bookID int64) (*Loan, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT ` + loanColumns + ` FROM cg_loans l
+						INNER JOIN cg_books b ON b.id = l.book_id
+						WHERE l.id = ? AND l.book_id = ?`
+
+	var loan Loan
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := scanLoan(l.DB.QueryRowContext(ctx, query, id, bookID), &loan)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &loan, nil
+}
+
+// UpdateLoan saves the loan. Reopening a returned loan while the book is out again
+// returns ErrBookLentOut.
+func (l *LoanModel) UpdateLoan(loan *Loan) error {
+	query := `UPDATE cg_loans SET borrower_name = TRIM(?), borrower_contact = TRIM(?), lent_at = ?, due_at = ?, returned_at = ?, updated_at = UTC_TIMESTAMP() WHERE id = ?`
+
+	args := []any{loan.BorrowerName, loan.BorrowerContact, loan.LentAt, nullableDate(loan.DueAt), nullableDate(loan.ReturnedAt), loan.ID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := l.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			return ErrBookLentOut
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// scanLoan scans a row selected with loanColumns.
+func scanLoan(row interface{ Scan(...any) error }, loan *Loan) error {
+	var lentAt time.Time
+	var dueAt, returnedAt sql.NullTime
+
+	err := row.Scan(&loan.ID, &loan.BookID, &loan.BookTitle, &loan.BorrowerName, &loan.BorrowerContact, &lentAt, &dueAt, &returnedAt, &loan.Overdue, &loan.CreatedAt, &loan.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	loan.LentAt = lentAt.Format(dateFinishedLayout)
+	if dueAt.Valid {
+		loan.DueAt = dueAt.Time.Format(dateFinishedLayout)
+	}
+	if returnedAt.Valid {
+		loan.ReturnedAt = returnedAt.Time.Format(dateFinishedLayout)
+	}
+
+	return nil
+}
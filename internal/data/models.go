@@ -1,24 +1,134 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 )
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
 )
 
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so the same model methods can run
+// either directly against the pool or inside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type Models struct {
-	Book     BookModel
-	Author   AuthorModel
-	Category CategoryModel
+	Book       BookModel
+	Author     AuthorModel
+	Category   CategoryModel
+	Goal       GoalModel
+	Library    LibraryModel
+	Loan       LoanModel
+	Permission PermissionModel
+	Publisher  PublisherModel
+	Quote      QuoteModel
+	Reading    ReadingModel
+	Review     ReviewModel
+	Search     SearchModel
+	Series     SeriesModel
+	Shelf      ShelfModel
+	Tag        TagModel
+	Token      TokenModel
+	User       UserModel
+	Work       WorkModel
+
+	db *sql.DB
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Book:     BookModel{DB: db},
-		Author:   AuthorModel{DB: db},
-		Category: CategoryModel{DB: db},
+		Book:       BookModel{DB: db},
+		Author:     AuthorModel{DB: db},
+		Category:   CategoryModel{DB: db},
+		Goal:       GoalModel{DB: db},
+		Library:    LibraryModel{DB: db},
+		Loan:       LoanModel{DB: db},
+		Permission: PermissionModel{DB: db},
+		Publisher:  PublisherModel{DB: db},
+		Quote:      QuoteModel{DB: db},
+		Reading:    ReadingModel{DB: db},
+		Review:     ReviewModel{DB: db},
+		Search:     SearchModel{DB: db},
+		Series:     SeriesModel{DB: db},
+		Shelf:      ShelfModel{DB: db},
+		Tag:        TagModel{DB: db},
+		Token:      TokenModel{DB: db},
+		User:       UserModel{DB: db},
+		Work:       WorkModel{DB: db},
+		db:         db,
+	}
+}
+
+// withDB returns a copy of the models with every model running its queries against
+// conn.
+func (m Models) withDB(conn DBTX) Models {
+	m.Book.DB = conn
+	m.Author.DB = conn
+	m.Category.DB = conn
+	m.Goal.DB = conn
+	m.Library.DB = conn
+	m.Loan.DB = conn
+	m.Permission.DB = conn
+	m.Publisher.DB = conn
+	m.Quote.DB = conn
+	m.Reading.DB = conn
+	m.Review.DB = conn
+	m.Search.DB = conn
+	m.Series.DB = conn
+	m.Shelf.DB = conn
+	m.Tag.DB = conn
+	m.Token.DB = conn
+	m.User.DB = conn
+	m.Work.DB = conn
+
+	return m
+}
+
+// RunInTx calls fn with a set of models bound to a single transaction. The
+// transaction is committed if fn returns nil and rolled back otherwise, so either
+// every change made through the models is saved or none of them are.
+func (m Models) RunInTx(fn func(tx Models) error) error {
+	tx, err := m.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
 	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(m.withDB(tx))
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// inPlaceholders returns a "?,?,?" fragment for an IN clause together with the ids
+// as query arguments.
+func inPlaceholders(ids []int64) (string, []any) {
+	marks := make([]string, len(ids))
+	args := make([]any, len(ids))
+
+	for i, id := range ids {
+		marks[i] = "?"
+		args[i] = id
+	}
+
+	return strings.Join(marks, ","), args
 }
@@ -0,0 +1,239 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a database/sql driver that holds back the statements run inside a
+// transaction until it is committed, and fails the statements containing failOn.
+// It lets RunInTx be tested without a MySQL server.
+type fakeDB struct {
+	mu        sync.Mutex
+	failOn    string
+	executed  []string
+	committed []string
+	rollbacks int
+	lastID    int64
+}
+
+var errFakeExec = errors.New("fake exec failed")
+
+func (db *fakeDB) Open(name string) (driver.Conn, error) {
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct {
+	db      *fakeDB
+	inTx    bool
+	pending []string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake driver: prepare is not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.inTx = true
+	c.pending = nil
+	return &fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	query = strings.Join(strings.Fields(query), " ")
+	c.db.executed = append(c.db.executed, query)
+
+	if c.db.failOn != "" && strings.Contains(query, c.db.failOn) {
+		return nil, errFakeExec
+	}
+
+	if c.inTx {
+		c.pending = append(c.pending, query)
+	} else {
+		c.db.committed = append(c.db.committed, query)
+	}
+
+	c.db.lastID++
+	return fakeResult{id: c.db.lastID}, nil
+}
+
+type fakeResult struct {
+	id int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return r.id, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.conn.db.mu.Lock()
+	defer tx.conn.db.mu.Unlock()
+
+	tx.conn.db.committed = append(tx.conn.db.committed, tx.conn.pending...)
+	tx.conn.pending = nil
+	tx.conn.inTx = false
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.db.mu.Lock()
+	defer tx.conn.db.mu.Unlock()
+
+	tx.conn.db.rollbacks++
+	tx.conn.pending = nil
+	tx.conn.inTx = false
+	return nil
+}
+
+var registerFakeDriver sync.Once
+
+// newFakeModels returns models running against a fresh fakeDB.
+func newFakeModels(t *testing.T, failOn string) (Models, *fakeDB) {
+	t.Helper()
+
+	fake := &fakeDB{failOn: failOn}
+
+	registerFakeDriver.Do(func() {
+		sql.Register("fakedb", &fakeDriver{})
+	})
+	fakeDrivers.Store(t.Name(), fake)
+
+	db, err := sql.Open("fakedb", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	return NewModels(db), fake
+}
+
+// fakeDriver hands out the fakeDB registered under the data source name, so each
+// test gets its own.
+type fakeDriver struct{}
+
+var fakeDrivers sync.Map
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fake, ok := fakeDrivers.Load(name)
+	if !ok {
+		return nil, errors.New("fake driver: unknown database " + name)
+	}
+	return fake.(*fakeDB).Open(name)
+}
+
+// insertBookWithLinks writes a book the way createBookHandler does: the work, the
+// edition and then the join tables.
+func insertBookWithLinks(tx Models) error {
+	workID, err := tx.Work.Insert(&Work{LibraryID: 1, Title: "Dune"})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Book.Insert(&Book{LibraryID: 1, WorkID: int64(workID), Title: "Dune", Ownership: OwnershipOwned})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Author.InsertWorkAuthors([]WorkAuthor{{WorkId: int64(workID), AuthorId: 7}})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Category.InsertWorkCategories([]WorkCategory{{WorkId: int64(workID), CategoryId: 3}})
+	return err
+}
+
+func TestRunInTxRollsBackWhenALinkFails(t *testing.T) {
+	models, fake := newFakeModels(t, "INSERT INTO cg_work_categories")
+
+	err := models.RunInTx(insertBookWithLinks)
+	if !errors.Is(err, errFakeExec) {
+		t.Fatalf("got error %v; want %v", err, errFakeExec)
+	}
+
+	if !containsStatement(fake.executed, "INSERT INTO cg_books") {
+		t.Fatalf("the book insert didn't run before the failure: %q", fake.executed)
+	}
+
+	if fake.rollbacks != 1 {
+		t.Errorf("got %d rollbacks; want 1", fake.rollbacks)
+	}
+
+	if len(fake.committed) != 0 {
+		t.Errorf("statements were committed after the failure: %q", fake.committed)
+	}
+}
+
+func TestRunInTxCommits(t *testing.T) {
+	models, fake := newFakeModels(t, "")
+
+	err := models.RunInTx(insertBookWithLinks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"cg_works", "cg_books", "cg_work_authors", "cg_work_categories"} {
+		if !containsStatement(fake.committed, "INSERT INTO "+table) {
+			t.Errorf("the insert into %s wasn't committed: %q", table, fake.committed)
+		}
+	}
+
+	if fake.rollbacks != 0 {
+		t.Errorf("got %d rollbacks; want 0", fake.rollbacks)
+	}
+}
+
+func TestRunInTxRollsBackOnPanic(t *testing.T) {
+	models, fake := newFakeModels(t, "")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("the panic wasn't passed on")
+		}
+		if fake.rollbacks != 1 || len(fake.committed) != 0 {
+			t.Errorf("got %d rollbacks and committed %q; want a rollback and nothing committed", fake.rollbacks, fake.committed)
+		}
+	}()
+
+	_ = models.RunInTx(func(tx Models) error {
+		_, err := tx.Book.Insert(&Book{LibraryID: 1, WorkID: 1, Title: "Dune"})
+		if err != nil {
+			return err
+		}
+		panic("boom")
+	})
+}
+
+func containsStatement(statements []string, prefix string) bool {
+	for _, s := range statements {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	PermissionBooksRead       = "books:read"
+	PermissionBooksWrite      = "books:write"
+	PermissionAuthorsWrite    = "authors:write"
+	PermissionCategoriesWrite = "categories:write"
+	PermissionReadingWrite    = "reading:write"
+	PermissionAdmin           = "admin"
+)
+
+// AllPermissions lists every permission code seeded by the migrations.
+var AllPermissions = []string{PermissionBooksRead, PermissionBooksWrite, PermissionAuthorsWrite, PermissionCategoriesWrite, PermissionReadingWrite, PermissionAdmin}
+
+type Permissions []string
+
+// Include reports whether the permission code is in the slice. The admin
+// permission includes every other permission.
+func (p Permissions) Include(code string) bool {
+	for i := range p {
+		if code == p[i] || p[i] == PermissionAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type PermissionModel struct {
+	DB DBTX
+}
+
+func (p *PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
+	query := `SELECT p.code FROM cg_permissions p
+						INNER JOIN cg_users_permissions up ON up.permission_id = p.id
+						WHERE up.user_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var permissions Permissions
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// AddForUser grants the permission codes to the user, ignoring ones it already has.
+func (p *PermissionModel) AddForUser(userID int64, codes ...string) error {
+	if len(codes) == 0 {
+		return nil
+	}
+
+	query := `INSERT IGNORE INTO cg_users_permissions (user_id, permission_id)
+						SELECT ?, p.id FROM cg_permissions p WHERE p.code IN (`
+
+	args := []any{userID}
+	for _, code := range codes {
+		query += `?,`
+		args = append(args, code)
+	}
+	query = query[:len(query)-1] + `)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// RemoveAllForUser revokes every permission of the user.
+func (p *PermissionModel) RemoveAllForUser(userID int64) error {
+	query := `DELETE FROM cg_users_permissions WHERE user_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,416 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+var ErrUnknownPublisher = errors.New("unknown publisher")
+
+type Publisher struct {
+	ID               int64     `json:"id"`
+	Name             string    `json:"name"`
+	BooksByPublisher int       `json:"books_by_publisher,omitempty"`
+	DateAdded        string    `json:"date_added"`
+	DateUpdated      string    `json:"date_updated"`
+	CreatedAt        time.Time `json:"-"`
+	UpdatedAt        time.Time `json:"-"`
+}
+
+type BookPublisher struct {
+	ID          int64     `json:"id"`
+	BookId      int64     `json:"book_id"`
+	PublisherId int64     `json:"publisher_id"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+type PublisherModel struct {
+	DB DBTX
+}
+
+func ValidatePublisher(v *validator.Validator, publisher *Publisher) {
+
+	v.Check(publisher.Name != "", "name", "Name cannot be empty")
+	v.Check(len(publisher.Name) <= 255, "name", "Name must not be more than 255 characters long")
+
+}
+
+func (p *PublisherModel) Insert(publisher *Publisher) (int, error) {
+	query := `INSERT INTO cg_publisher (name,created_at,updated_at) VALUES (TRIM(?), UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	args := []any{publisher.Name}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := p.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (p *PublisherModel) InsertBookPublishers(bp []BookPublisher) (int, error) {
+	if len(bp) == 0 {
+		return 0, nil
+	}
+
+	query := `INSERT INTO cg_book_publisher (book_id, publisher_id, created_at, updated_at) VALUES`
+
+	args := []any{}
+
+	for _, v := range bp {
+		args = append(args, v.BookId, v.PublisherId)
+		query += `(?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP()),`
+	}
+	query = query[:len(query)-1]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := p.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		// a foreign key failure, one of the publisher ids doesn't exist
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1452:
+			return 0, ErrUnknownPublisher
+		default:
+			return 0, err
+		}
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (p *PublisherModel) GetPublishers() ([]*Publisher, error) {
+	query := `SELECT id, name, created_at, updated_at FROM cg_publisher`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	publishers := []*Publisher{}
+
+	for rows.Next() {
+		pub := &Publisher{}
+
+		err = rows.Scan(&pub.ID, &pub.Name, &pub.CreatedAt, &pub.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, pub)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return publishers, nil
+}
+
+func (p *PublisherModel) GetPublisher(id int64) (*Publisher, error) {
+
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, name, created_at, updated_at FROM cg_publisher WHERE id = ?`
+
+	var publisher Publisher
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := p.DB.QueryRowContext(ctx, query, id).Scan(&publisher.ID, &publisher.Name, &publisher.CreatedAt, &publisher.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &publisher, nil
+}
+
+// GetPublisherIDByName finds a publisher by name, ignoring case. When several
+// publishers share the name the oldest one is returned.
+func (p *PublisherModel) GetPublisherIDByName(name string) (int64, error) {
+	query := `SELECT id FROM cg_publisher WHERE LOWER(name) = LOWER(TRIM(?)) ORDER BY id LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	var id int64
+
+	err := p.DB.QueryRowContext(ctx, query, name).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+// GetBooksByPublisher counts the publisher's books in the library.
+func (p *PublisherModel) GetBooksByPublisher(id int64, libraryID int64) (int, error) {
+	query := `SELECT COUNT(b.id) FROM cg_books b
+						INNER JOIN cg_book_publisher bp ON bp.book_id = b.id
+						WHERE bp.publisher_id = ? AND b.library_id = ?`
+
+	var bookPublisherNumber int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := p.DB.QueryRowContext(ctx, query, id, libraryID).Scan(&bookPublisherNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	return bookPublisherNumber, nil
+}
+
+func (p *PublisherModel) GetBookPublishers(id int64) ([]*Publisher, error) {
+	query := `SELECT p.id, p.name, p.created_at, p.updated_at FROM cg_publisher p
+						INNER JOIN cg_book_publisher bp ON bp.publisher_id = p.id
+						WHERE bp.book_id = ?
+						ORDER BY bp.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := p.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer results.Close()
+
+	publishers := []*Publisher{}
+
+	for results.Next() {
+		pub := &Publisher{}
+
+		err := results.Scan(&pub.ID, &pub.Name, &pub.CreatedAt, &pub.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, pub)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, err
+	}
+
+	return publishers, nil
+}
+
+// UsedOutsideLibrary reports whether any of the publishers in ids is linked to books in
+// another library than libraryID.
+func (p *PublisherModel) UsedOutsideLibrary(ids []int64, libraryID int64) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT EXISTS (SELECT 1 FROM cg_book_publisher bp INNER JOIN cg_books b ON b.id = bp.book_id WHERE bp.publisher_id IN (` + in + `) AND b.library_id <> ?)`
+
+	var used bool
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := p.DB.QueryRowContext(ctx, query, append(args, libraryID)...).Scan(&used)
+	if err != nil {
+		return false, err
+	}
+
+	return used, nil
+}
+
+func (p *PublisherModel) UpdatePublisher(publisher *Publisher) error {
+	query := `UPDATE cg_publisher SET name = TRIM(?), updated_at = UTC_TIMESTAMP() WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, query, publisher.Name, publisher.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *PublisherModel) DeletePublisher(id int64) error {
+	if id < 0 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM cg_publisher WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := p.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteBookPublishers removes every publisher link for a book. Unlike the
+// other Delete* methods it doesn't report ErrRecordNotFound, since a book
+// without a publisher is perfectly valid.
+func (p *PublisherModel) DeleteBookPublishers(id int64) error {
+	if id < 0 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM cg_book_publisher WHERE book_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetPublishersForBooks loads the publishers of every book in ids with a single
+// query, keyed by book id.
+func (p *PublisherModel) GetPublishersForBooks(ids []int64) (map[int64][]*Publisher, error) {
+	publishers := make(map[int64][]*Publisher, len(ids))
+	if len(ids) == 0 {
+		return publishers, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT bp.book_id, p.id, p.name, p.created_at, p.updated_at FROM cg_publisher p
+						INNER JOIN cg_book_publisher bp ON bp.publisher_id = p.id
+						WHERE bp.book_id IN (` + in + `)
+						ORDER BY bp.book_id, bp.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	results, err := p.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer results.Close()
+
+	for results.Next() {
+		var bookId int64
+		pub := &Publisher{}
+
+		err := results.Scan(&bookId, &pub.ID, &pub.Name, &pub.CreatedAt, &pub.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		publishers[bookId] = append(publishers[bookId], pub)
+	}
+
+	if err = results.Err(); err != nil {
+		return nil, err
+	}
+
+	return publishers, nil
+}
+
+// GetBooksByPublishers counts the books in the library of every publisher in one
+// GROUP BY query, keyed by publisher id. Publishers without books are missing from
+// the map.
+func (p *PublisherModel) GetBooksByPublishers(libraryID int64) (map[int64]int, error) {
+	query := `SELECT bp.publisher_id, COUNT(DISTINCT bp.book_id) FROM cg_book_publisher bp
+						INNER JOIN cg_books b ON b.id = bp.book_id
+						WHERE b.library_id = ?
+						GROUP BY bp.publisher_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, libraryID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := map[int64]int{}
+
+	for rows.Next() {
+		var publisherId int64
+		var count int
+
+		err := rows.Scan(&publisherId, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[publisherId] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
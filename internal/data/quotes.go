@@ -0,0 +1,233 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+type Quote struct {
+	ID          int64     `json:"id"`
+	BookID      int64     `json:"book_id"`
+	BookTitle   string    `json:"book_title"`
+	UserID      int64     `json:"user_id"`
+	Text        string    `json:"text"`
+	Page        int       `json:"page,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	Snippet     string    `json:"snippet,omitempty"`
+	DateAdded   string    `json:"date_added"`
+	DateUpdated string    `json:"date_updated"`
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+// ValidateQuote checks the quote. The page and location both place the quote in
+// the book, the location is free-form for e-readers ("loc 1234", "ch. 3").
+func ValidateQuote(v *validator.Validator, quote *Quote) {
+	v.Check(strings.TrimSpace(quote.Text) != "", "text", "must be provided")
+	v.Check(len(quote.Text) <= 65_535, "text", "must not be more than 65535 bytes long")
+	v.Check(quote.Page >= 0, "page", "must not be negative")
+	v.Check(len(quote.Location) <= 50, "location", "must not be more than 50 bytes long")
+	v.Check(len(quote.Note) <= 65_535, "note", "must not be more than 65535 bytes long")
+}
+
+type QuoteModel struct {
+	DB DBTX
+}
+
+// quoteColumns is shared by the quote queries, which join the book as b.
+const quoteColumns = `q.id, q.book_id, b.title, q.user_id, q.text, COALESCE(q.page, 0), COALESCE(q.location, ''), COALESCE(q.note, ''), q.created_at, q.updated_at`
+
+func (qt *Quote) scanDest() []any {
+	return []any{&qt.ID, &qt.BookID, &qt.BookTitle, &qt.UserID, &qt.Text, &qt.Page, &qt.Location, &qt.Note, &qt.CreatedAt, &qt.UpdatedAt}
+}
+
+func (qm *QuoteModel) Insert(quote *Quote) (int, error) {
+	query := `INSERT INTO cg_quotes (user_id, book_id, text, page, location, note, created_at, updated_at) VALUES (?, ?, TRIM(?), NULLIF(?, 0), NULLIF(TRIM(?), ''), NULLIF(TRIM(?), ''), UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	args := []any{quote.UserID, quote.BookID, quote.Text, quote.Page, quote.Location, quote.Note}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := qm.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetBookQuotes returns the user's quotes from the book in the order they appear
+// in it, quotes without a page come last.
+func (qm *QuoteModel) GetBookQuotes(bookID int64, userID int64) ([]*Quote, error) {
+	query := `SELECT ` + quoteColumns + ` FROM cg_quotes q
+						INNER JOIN cg_books b ON b.id = q.book_id
+						WHERE q.book_id = ? AND q.user_id = ?
+						ORDER BY q.page IS NULL, q.page, q.id`
+
+	return qm.getQuotes(query, bookID, userID)
+}
+
+// SearchQuotes returns the user's quotes from books in the library whose text or
+// note match q, best matches first, with a highlighted snippet of the text.
+// Without q the most recent quotes are returned.
+func (qm *QuoteModel) SearchQuotes(q string, limit int, userID int64, libraryID int64) ([]*Quote, error) {
+	if strings.TrimSpace(q) == "" {
+		query := `SELECT ` + quoteColumns + ` FROM cg_quotes q
+							INNER JOIN cg_books b ON b.id = q.book_id
+							WHERE q.user_id = ? AND b.library_id = ?
+							ORDER BY q.created_at DESC, q.id DESC
+							LIMIT ?`
+
+		return qm.getQuotes(query, userID, libraryID, limit)
+	}
+
+	query := `SELECT ` + quoteColumns + ` FROM cg_quotes q
+						INNER JOIN cg_books b ON b.id = q.book_id
+						WHERE MATCH(q.text, q.note) AGAINST (? IN NATURAL LANGUAGE MODE) AND q.user_id = ? AND b.library_id = ?
+						ORDER BY MATCH(q.text, q.note) AGAINST (? IN NATURAL LANGUAGE MODE) DESC, q.id
+						LIMIT ?`
+
+	quotes, err := qm.getQuotes(query, q, userID, libraryID, q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := searchTerms(q)
+	for _, quote := range quotes {
+		quote.Snippet = highlight(quote.Text, terms)
+	}
+
+	return quotes, nil
+}
+
+// RandomQuote picks one of the user's quotes from books in the library.
+func (qm *QuoteModel) RandomQuote(userID int64, libraryID int64) (*Quote, error) {
+	query := `SELECT ` + quoteColumns + ` FROM cg_quotes q
+						INNER JOIN cg_books b ON b.id = q.book_id
+						WHERE q.user_id = ? AND b.library_id = ?
+						ORDER BY RAND()
+						LIMIT 1`
+
+	return qm.getQuote(query, userID, libraryID)
+}
+
+func (qm *QuoteModel) GetQuote(id int64, bookID int64) (*Quote, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT ` + quoteColumns + ` FROM cg_quotes q
+						INNER JOIN cg_books b ON b.id = q.book_id
+						WHERE q.id = ? AND q.book_id = ?`
+
+	return qm.getQuote(query, id, bookID)
+}
+
+func (qm *QuoteModel) getQuote(query string, args ...any) (*Quote, error) {
+	var quote Quote
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := qm.DB.QueryRowContext(ctx, query, args...).Scan(quote.scanDest()...)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &quote, nil
+}
+
+func (qm *QuoteModel) getQuotes(query string, args ...any) ([]*Quote, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := qm.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	quotes := []*Quote{}
+
+	for rows.Next() {
+		quote := &Quote{}
+
+		err := rows.Scan(quote.scanDest()...)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, quote)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return quotes, nil
+}
+
+func (qm *QuoteModel) UpdateQuote(quote *Quote) error {
+	query := `UPDATE cg_quotes SET text = TRIM(?), page = NULLIF(?, 0), location = NULLIF(TRIM(?), ''), note = NULLIF(TRIM(?), ''), updated_at = UTC_TIMESTAMP() WHERE id = ?`
+
+	args := []any{quote.Text, quote.Page, quote.Location, quote.Note, quote.ID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := qm.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (qm *QuoteModel) DeleteQuote(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM cg_quotes WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := qm.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
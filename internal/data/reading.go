@@ -0,0 +1,187 @@
+package data
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+type ReadingSession struct {
+	ID        int64     `json:"id"`
+	BookID    int64     `json:"book_id"`
+	UserID    int64     `json:"-"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	StartPage int       `json:"start_page"`
+	EndPage   int       `json:"end_page"`
+	Minutes   int       `json:"minutes"`
+	CreatedAt time.Time `json:"-"`
+}
+
+type StatusChange struct {
+	Status    string    `json:"status"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ReadingProgress summarises the sessions of a user for a book.
+type ReadingProgress struct {
+	PagesRead       int     `json:"pages_read"`
+	PageCount       int     `json:"page_count"`
+	PercentComplete float64 `json:"percent_complete"`
+	Sessions        int     `json:"sessions"`
+	MinutesRead     int     `json:"minutes_read"`
+}
+
+func ValidateReadingSession(v *validator.Validator, session *ReadingSession, book *Book) {
+	v.Check(!session.StartedAt.IsZero(), "started_at", "must be provided")
+	v.Check(!session.EndedAt.IsZero(), "ended_at", "must be provided")
+	v.Check(!session.EndedAt.Before(session.StartedAt), "ended_at", "must not be before started_at")
+	v.Check(session.StartPage >= 0, "start_page", "must not be negative")
+	v.Check(session.EndPage >= session.StartPage, "end_page", "must not be before start_page")
+
+	if book.PageCount > 0 {
+		v.Check(session.EndPage <= book.PageCount, "end_page", "must not be past the last page of the book")
+	}
+}
+
+// NextStatus works out the reading status after a session: the first session moves
+// a book to "In progress" and reaching the last page marks it "Read".
+func NextStatus(current int, book *Book, session *ReadingSession) int {
+	if book.PageCount > 0 && session.EndPage >= book.PageCount {
+		return StatusRead
+	}
+
+	if current < StatusInProgress {
+		return StatusInProgress
+	}
+
+	return current
+}
+
+type ReadingModel struct {
+	DB DBTX
+}
+
+func (rm *ReadingModel) InsertSession(session *ReadingSession) error {
+	query := `INSERT INTO cg_reading_sessions (user_id, book_id, started_at, ended_at, start_page, end_page, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	args := []any{session.UserID, session.BookID, session.StartedAt.UTC(), session.EndedAt.UTC(), session.StartPage, session.EndPage}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := rm.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	session.ID = id
+	session.Minutes = int(session.EndedAt.Sub(session.StartedAt).Minutes())
+
+	return nil
+}
+
+// GetSessions returns the user's sessions for a book, oldest first.
+func (rm *ReadingModel) GetSessions(userID int64, bookID int64) ([]*ReadingSession, error) {
+	query := `SELECT id, book_id, user_id, started_at, ended_at, start_page, end_page, created_at FROM cg_reading_sessions
+						WHERE user_id = ? AND book_id = ?
+						ORDER BY started_at, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := rm.DB.QueryContext(ctx, query, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sessions := []*ReadingSession{}
+
+	for rows.Next() {
+		session := &ReadingSession{}
+
+		err := rows.Scan(&session.ID, &session.BookID, &session.UserID, &session.StartedAt, &session.EndedAt, &session.StartPage, &session.EndPage, &session.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		session.Minutes = int(session.EndedAt.Sub(session.StartedAt).Minutes())
+
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// GetStatusHistory returns the user's status changes for a book, oldest first.
+func (rm *ReadingModel) GetStatusHistory(userID int64, bookID int64) ([]*StatusChange, error) {
+	query := `SELECT status, changed_at FROM cg_status_history
+						WHERE user_id = ? AND book_id = ?
+						ORDER BY changed_at, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := rm.DB.QueryContext(ctx, query, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	history := []*StatusChange{}
+
+	for rows.Next() {
+		change := &StatusChange{}
+
+		err := rows.Scan(&change.Status, &change.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Progress computes how far through the book the sessions got. The furthest page
+// reached counts, so re-reading a chapter doesn't inflate the percentage.
+func Progress(book *Book, sessions []*ReadingSession) ReadingProgress {
+	progress := ReadingProgress{
+		PageCount: book.PageCount,
+		Sessions:  len(sessions),
+	}
+
+	for _, session := range sessions {
+		if session.EndPage > progress.PagesRead {
+			progress.PagesRead = session.EndPage
+		}
+		progress.MinutesRead += session.Minutes
+	}
+
+	if book.PageCount > 0 {
+		percent := float64(progress.PagesRead) / float64(book.PageCount) * 100
+		progress.PercentComplete = math.Round(math.Min(percent, 100)*10) / 10
+	}
+
+	return progress
+}
@@ -0,0 +1,203 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+var ErrDuplicateReview = errors.New("duplicate review")
+
+const dateFinishedLayout = "2006-01-02"
+
+type Review struct {
+	ID           int64     `json:"id"`
+	BookID       int64     `json:"book_id"`
+	UserID       int64     `json:"user_id"`
+	Reviewer     string    `json:"reviewer"`
+	Rating       float64   `json:"rating"`
+	Review       string    `json:"review"`
+	Spoiler      bool      `json:"spoiler"`
+	DateFinished string    `json:"date_finished,omitempty"`
+	DateAdded    string    `json:"date_added"`
+	DateUpdated  string    `json:"date_updated"`
+	CreatedAt    time.Time `json:"-"`
+	UpdatedAt    time.Time `json:"-"`
+}
+
+func ValidateReview(v *validator.Validator, review *Review) {
+	v.Check(review.Rating >= 1 && review.Rating <= 5, "rating", "must be between 1 and 5")
+	v.Check(review.Rating*2 == math.Trunc(review.Rating*2), "rating", "must be a whole or half star")
+	v.Check(len(review.Review) <= 65_535, "review", "must not be more than 65535 bytes long")
+
+	if review.DateFinished != "" {
+		finished, err := time.Parse(dateFinishedLayout, review.DateFinished)
+		v.Check(err == nil, "date_finished", "must be a date in the format YYYY-MM-DD")
+		v.Check(err != nil || !finished.After(time.Now()), "date_finished", "must not be in the future")
+	}
+}
+
+type ReviewModel struct {
+	DB DBTX
+}
+
+// nullableDate turns an empty date_finished into NULL.
+func nullableDate(date string) any {
+	if date == "" {
+		return nil
+	}
+	return date
+}
+
+func (rm *ReviewModel) Insert(review *Review) (int, error) {
+	query := `INSERT INTO cg_reviews (user_id, book_id, rating, review, spoiler, date_finished, created_at, updated_at) VALUES (?, ?, ?, TRIM(?), ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	args := []any{review.UserID, review.BookID, review.Rating, review.Review, review.Spoiler, nullableDate(review.DateFinished)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := rm.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			return 0, ErrDuplicateReview
+		default:
+			return 0, err
+		}
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetBookReviews returns every review of the book, newest first.
+func (rm *ReviewModel) GetBookReviews(bookID int64) ([]*Review, error) {
+	query := `SELECT r.id, r.book_id, r.user_id, u.name, r.rating, COALESCE(r.review, ''), r.spoiler, r.date_finished, r.created_at, r.updated_at FROM cg_reviews r
+						INNER JOIN cg_users u ON u.id = r.user_id
+						WHERE r.book_id = ?
+						ORDER BY r.created_at DESC, r.id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := rm.DB.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	reviews := []*Review{}
+
+	for rows.Next() {
+		review := &Review{}
+		var dateFinished sql.NullTime
+
+		err := rows.Scan(&review.ID, &review.BookID, &review.UserID, &review.Reviewer, &review.Rating, &review.Review, &review.Spoiler, &dateFinished, &review.CreatedAt, &review.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if dateFinished.Valid {
+			review.DateFinished = dateFinished.Time.Format(dateFinishedLayout)
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+func (rm *ReviewModel) GetReview(id int64, bookID int64) (*Review, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT r.id, r.book_id, r.user_id, u.name, r.rating, COALESCE(r.review, ''), r.spoiler, r.date_finished, r.created_at, r.updated_at FROM cg_reviews r
+						INNER JOIN cg_users u ON u.id = r.user_id
+						WHERE r.id = ? AND r.book_id = ?`
+
+	var review Review
+	var dateFinished sql.NullTime
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := rm.DB.QueryRowContext(ctx, query, id, bookID).Scan(&review.ID, &review.BookID, &review.UserID, &review.Reviewer, &review.Rating, &review.Review, &review.Spoiler, &dateFinished, &review.CreatedAt, &review.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if dateFinished.Valid {
+		review.DateFinished = dateFinished.Time.Format(dateFinishedLayout)
+	}
+
+	return &review, nil
+}
+
+func (rm *ReviewModel) UpdateReview(review *Review) error {
+	query := `UPDATE cg_reviews SET rating = ?, review = TRIM(?), spoiler = ?, date_finished = ?, updated_at = UTC_TIMESTAMP() WHERE id = ?`
+
+	args := []any{review.Rating, review.Review, review.Spoiler, nullableDate(review.DateFinished), review.ID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := rm.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rm *ReviewModel) DeleteReview(id int64) error {
+	if id < 0 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM cg_reviews WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := rm.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
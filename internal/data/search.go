@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+const snippetLength = 160
+
+type SearchHit struct {
+	Type    string  `json:"type"`
+	ID      int64   `json:"id"`
+	Label   string  `json:"label"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchModel struct {
+	DB DBTX
+}
+
+func ValidateSearch(v *validator.Validator, q string, limit int) {
+	v.Check(strings.TrimSpace(q) != "", "q", "must be provided")
+	v.Check(len(q) <= 255, "q", "must not be more than 255 characters long")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 100, "limit", "must be a maximum of 100")
+}
+
+// Search ranks books in the library, authors and categories against q using the
+// FULLTEXT indexes and returns the best matches first.
+func (s *SearchModel) Search(q string, limit int, libraryID int64) ([]*SearchHit, error) {
+	query := `
+	SELECT type, id, label, body, score FROM (
+		SELECT 'book' AS type, id, title AS label, CONCAT_WS(' ', subtitle, description) AS body,
+			MATCH(title, subtitle, description, isbn) AGAINST (? IN NATURAL LANGUAGE MODE) AS score
+		FROM cg_books
+		WHERE MATCH(title, subtitle, description, isbn) AGAINST (? IN NATURAL LANGUAGE MODE) AND library_id = ?
+		UNION ALL
+		SELECT 'author', id, CONCAT(first_name, ' ', last_name), description,
+			MATCH(first_name, last_name, description) AGAINST (? IN NATURAL LANGUAGE MODE)
+		FROM cg_authors
+		WHERE MATCH(first_name, last_name, description) AGAINST (? IN NATURAL LANGUAGE MODE)
+		UNION ALL
+		SELECT 'category', id, name, name,
+			MATCH(name) AGAINST (? IN NATURAL LANGUAGE MODE)
+		FROM cg_categories
+		WHERE MATCH(name) AGAINST (? IN NATURAL LANGUAGE MODE)
+	) hits
+	ORDER BY score DESC, type, id
+	LIMIT ?`
+
+	args := []any{q, q, libraryID, q, q, q, q, limit}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	terms := searchTerms(q)
+	hits := []*SearchHit{}
+
+	for rows.Next() {
+		hit := &SearchHit{}
+		var body sql.NullString
+
+		err := rows.Scan(&hit.Type, &hit.ID, &hit.Label, &body, &hit.Score)
+		if err != nil {
+			return nil, err
+		}
+
+		text := body.String
+		if strings.TrimSpace(text) == "" {
+			text = hit.Label
+		}
+		hit.Snippet = highlight(text, terms)
+
+		hits = append(hits, hit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+// searchTerms splits the search query into lower-cased words.
+func searchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// highlight cuts a window of text around the first matching term and wraps every
+// occurrence of a term in <mark> tags. The text is HTML escaped first so the
+// snippet can be rendered as-is.
+func highlight(text string, terms []string) string {
+	runes := []rune(text)
+
+	start := 0
+	for i := range runes {
+		if matchTerm(runes[i:], terms) > 0 {
+			start = i
+			break
+		}
+	}
+
+	// keep a little context before the first match
+	start -= snippetLength / 4
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetLength
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	for i := start; i < end; {
+		n := matchTerm(runes[i:end], terms)
+		if n > 0 {
+			b.WriteString("<mark>" + html.EscapeString(string(runes[i:i+n])) + "</mark>")
+			i += n
+			continue
+		}
+
+		b.WriteString(html.EscapeString(string(runes[i])))
+		i++
+	}
+
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+// matchTerm returns the length in runes of the longest term found at the start of
+// text, or 0 when none of the terms match.
+func matchTerm(text []rune, terms []string) int {
+	longest := 0
+
+	for _, term := range terms {
+		n := len([]rune(term))
+		if n > longest && n <= len(text) && strings.EqualFold(string(text[:n]), term) {
+			longest = n
+		}
+	}
+
+	return longest
+}
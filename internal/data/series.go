@@ -0,0 +1,294 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+type Series struct {
+	ID            int64     `json:"id"`
+	LibraryID     int64     `json:"library_id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	BooksInSeries int       `json:"books_in_series,omitempty"`
+	Books         []*Book   `json:"books,omitempty"`
+	DateAdded     string    `json:"date_added"`
+	DateUpdated   string    `json:"date_updated"`
+	CreatedAt     time.Time `json:"-"`
+	UpdatedAt     time.Time `json:"-"`
+}
+
+// BookSeries is a series as embedded in a book, with the book's position in it.
+// Positions are decimals so novellas can sit between volumes, e.g. 2.5.
+type BookSeries struct {
+	ID       int64   `json:"id"`
+	Name     string  `json:"name"`
+	Position float64 `json:"position"`
+}
+
+// SeriesEntry is the series and position a book is assigned to on create/update.
+type SeriesEntry struct {
+	ID       int64   `json:"id"`
+	Position float64 `json:"position"`
+}
+
+type SeriesModel struct {
+	DB DBTX
+}
+
+func ValidateSeries(v *validator.Validator, series *Series) {
+
+	v.Check(series.Name != "", "name", "Name cannot be empty")
+	v.Check(len(series.Name) <= 255, "name", "Name must not be more than 255 characters long")
+
+}
+
+func ValidateSeriesEntries(v *validator.Validator, entries []SeriesEntry) {
+	ids := make([]int64, len(entries))
+
+	for i, entry := range entries {
+		ids[i] = entry.ID
+		v.Check(entry.ID > 0, "series", "must contain valid series ids")
+		// the column is decimal(6,2) and MySQL rounds to 2 places on insert, so
+		// 9999.995 is out of range as well
+		position := math.Round(entry.Position*100) / 100
+		v.Check(position >= 0 && position <= 9999.99, "series", "position must be between 0 and 9999.99")
+	}
+
+	v.Check(validator.Unique(ids), "series", "must not contain duplicate values")
+}
+
+func (s *SeriesModel) Insert(series *Series) (int, error) {
+	query := `INSERT INTO cg_series (library_id,name,description,created_at,updated_at) VALUES (?, TRIM(?), TRIM(?), UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, series.LibraryID, series.Name, series.Description)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (s *SeriesModel) InsertBookSeries(bookID int64, entries []SeriesEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO cg_book_series (book_id, series_id, position, created_at, updated_at) VALUES`
+
+	args := []any{}
+
+	for _, entry := range entries {
+		args = append(args, bookID, entry.ID, entry.Position)
+		query += `(?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP()),`
+	}
+	query = query[:len(query)-1]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetAllSeries returns every series of the library with the number of books in it.
+func (s *SeriesModel) GetAllSeries(libraryID int64) ([]*Series, error) {
+	query := `SELECT s.id, s.library_id, s.name, COALESCE(s.description, ''), COUNT(bs.book_id), s.created_at, s.updated_at FROM cg_series s
+						LEFT JOIN cg_book_series bs ON bs.series_id = s.id
+						WHERE s.library_id = ?
+						GROUP BY s.id
+						ORDER BY s.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, libraryID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	allSeries := []*Series{}
+
+	for rows.Next() {
+		series := &Series{}
+
+		err := rows.Scan(&series.ID, &series.LibraryID, &series.Name, &series.Description, &series.BooksInSeries, &series.CreatedAt, &series.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		allSeries = append(allSeries, series)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return allSeries, nil
+}
+
+func (s *SeriesModel) GetSeries(id int64, libraryID int64) (*Series, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, library_id, name, COALESCE(description, ''), created_at, updated_at FROM cg_series WHERE id = ? AND library_id = ?`
+
+	var series Series
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, id, libraryID).Scan(&series.ID, &series.LibraryID, &series.Name, &series.Description, &series.CreatedAt, &series.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &series, nil
+}
+
+// GetSeriesForBooks loads the series of every book in ids with a single query,
+// keyed by book id.
+func (s *SeriesModel) GetSeriesForBooks(ids []int64) (map[int64][]*BookSeries, error) {
+	series := make(map[int64][]*BookSeries, len(ids))
+	if len(ids) == 0 {
+		return series, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT bs.book_id, s.id, s.name, bs.position FROM cg_series s
+						INNER JOIN cg_book_series bs ON bs.series_id = s.id
+						WHERE bs.book_id IN (` + in + `)
+						ORDER BY bs.book_id, s.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookId int64
+		bs := &BookSeries{}
+
+		err := rows.Scan(&bookId, &bs.ID, &bs.Name, &bs.Position)
+		if err != nil {
+			return nil, err
+		}
+		series[bookId] = append(series[bookId], bs)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func (s *SeriesModel) UpdateSeries(series *Series) error {
+	query := `UPDATE cg_series SET name = TRIM(?), description = TRIM(?), updated_at = UTC_TIMESTAMP() WHERE id = ? AND library_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, series.Name, series.Description, series.ID, series.LibraryID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *SeriesModel) DeleteSeries(id int64, libraryID int64) error {
+	if id < 0 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM cg_series WHERE id = ? AND library_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, id, libraryID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// InLibrary reports whether every series in ids belongs to the library.
+func (s *SeriesModel) InLibrary(ids []int64, libraryID int64) (bool, error) {
+	if len(ids) == 0 {
+		return true, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT COUNT(DISTINCT id) FROM cg_series WHERE library_id = ? AND id IN (` + in + `)`
+
+	var count int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, append([]any{libraryID}, args...)...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count == len(ids), nil
+}
+
+// DeleteBookSeries removes every series link for a book.
+func (s *SeriesModel) DeleteBookSeries(bookID int64) error {
+	query := `DELETE FROM cg_book_series WHERE book_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, bookID)
+	return err
+}
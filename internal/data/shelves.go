@@ -0,0 +1,337 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// Shelf is a user's ordered list of books, e.g. "Summer 2026". A shelf with a
+// share token can be read by anyone who has the token.
+type Shelf struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	LibraryID    int64     `json:"-"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description,omitempty"`
+	BooksOnShelf int       `json:"books_on_shelf"`
+	Shared       bool      `json:"shared"`
+	Books        []*Book   `json:"books,omitempty"`
+	DateAdded    string    `json:"date_added"`
+	DateUpdated  string    `json:"date_updated"`
+	CreatedAt    time.Time `json:"-"`
+	UpdatedAt    time.Time `json:"-"`
+}
+
+// BookShelf is a shelf as embedded in a book, with the book's position on it.
+type BookShelf struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+// ShelfEntry is a book and the position it should have on a shelf.
+type ShelfEntry struct {
+	ID       int64 `json:"id"`
+	Position int   `json:"position"`
+}
+
+type ShelfModel struct {
+	DB DBTX
+}
+
+func ValidateShelf(v *validator.Validator, shelf *Shelf) {
+
+	v.Check(shelf.Name != "", "name", "Name cannot be empty")
+	v.Check(len(shelf.Name) <= 255, "name", "Name must not be more than 255 characters long")
+
+}
+
+func ValidateShelfEntries(v *validator.Validator, entries []ShelfEntry, remove []int64) {
+	ids := make([]int64, len(entries))
+
+	for i, entry := range entries {
+		ids[i] = entry.ID
+		v.Check(entry.ID > 0, "books", "must contain valid book ids")
+		v.Check(entry.Position >= 0, "books", "position must not be negative")
+	}
+
+	v.Check(validator.Unique(ids), "books", "must not contain duplicate values")
+
+	adding := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		adding[id] = true
+	}
+
+	for _, id := range remove {
+		v.Check(id > 0, "remove", "must contain valid book ids")
+		v.Check(!adding[id], "remove", "must not contain books that are also being added")
+	}
+
+	v.Check(validator.Unique(remove), "remove", "must not contain duplicate values")
+}
+
+func (s *ShelfModel) Insert(shelf *Shelf) (int, error) {
+	query := `INSERT INTO cg_shelves (user_id,name,description,created_at,updated_at) VALUES (?, TRIM(?), TRIM(?), UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, shelf.UserID, shelf.Name, shelf.Description)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetShelves returns the user's shelves with the number of books on each.
+func (s *ShelfModel) GetShelves(userID int64) ([]*Shelf, error) {
+	query := `SELECT s.id, s.user_id, u.library_id, s.name, COALESCE(s.description, ''), COUNT(sb.book_id), s.share_hash IS NOT NULL, s.created_at, s.updated_at FROM cg_shelves s
+						INNER JOIN cg_users u ON u.id = s.user_id
+						LEFT JOIN cg_shelf_books sb ON sb.shelf_id = s.id
+						WHERE s.user_id = ?
+						GROUP BY s.id
+						ORDER BY s.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	shelves := []*Shelf{}
+
+	for rows.Next() {
+		shelf := &Shelf{}
+
+		err := rows.Scan(&shelf.ID, &shelf.UserID, &shelf.LibraryID, &shelf.Name, &shelf.Description, &shelf.BooksOnShelf, &shelf.Shared, &shelf.CreatedAt, &shelf.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		shelves = append(shelves, shelf)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shelves, nil
+}
+
+// GetShelf returns the shelf if it belongs to the user
+func (s *ShelfModel) GetShelf(id int64, userID int64) (*Shelf, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT s.id, s.user_id, u.library_id, s.name, COALESCE(s.description, ''), s.share_hash IS NOT NULL, s.created_at, s.updated_at FROM cg_shelves s
+						INNER JOIN cg_users u ON u.id = s.user_id
+						WHERE s.id = ? AND s.user_id = ?`
+
+	return s.getShelf(query, id, userID)
+}
+
+// GetShelfForShareToken returns the shelf shared with the plaintext token.
+func (s *ShelfModel) GetShelfForShareToken(tokenPlaintext string) (*Shelf, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `SELECT s.id, s.user_id, u.library_id, s.name, COALESCE(s.description, ''), s.share_hash IS NOT NULL, s.created_at, s.updated_at FROM cg_shelves s
+						INNER JOIN cg_users u ON u.id = s.user_id
+						WHERE s.share_hash = ?`
+
+	return s.getShelf(query, tokenHash[:])
+}
+
+func (s *ShelfModel) getShelf(query string, args ...any) (*Shelf, error) {
+	var shelf Shelf
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, args...).Scan(&shelf.ID, &shelf.UserID, &shelf.LibraryID, &shelf.Name, &shelf.Description, &shelf.Shared, &shelf.CreatedAt, &shelf.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &shelf, nil
+}
+
+func (s *ShelfModel) UpdateShelf(shelf *Shelf) error {
+	query := `UPDATE cg_shelves SET name = TRIM(?), description = TRIM(?), updated_at = UTC_TIMESTAMP() WHERE id = ? AND user_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, shelf.Name, shelf.Description, shelf.ID, shelf.UserID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteShelf deletes the shelf if it belongs to the user
+func (s *ShelfModel) DeleteShelf(id int64, userID int64) error {
+	if id < 0 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM cg_shelves WHERE id = ? AND user_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// SetBookPositions adds the books to the shelf, or moves them if they are already
+// on it.
+func (s *ShelfModel) SetBookPositions(shelfID int64, entries []ShelfEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO cg_shelf_books (shelf_id, book_id, position, created_at, updated_at) VALUES`
+
+	args := []any{}
+
+	for _, entry := range entries {
+		args = append(args, shelfID, entry.ID, entry.Position)
+		query += `(?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP()),`
+	}
+	query = query[:len(query)-1] + ` ON DUPLICATE KEY UPDATE position = VALUES(position), updated_at = UTC_TIMESTAMP()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// RemoveBooks takes the books off the shelf.
+func (s *ShelfModel) RemoveBooks(shelfID int64, bookIDs []int64) error {
+	if len(bookIDs) == 0 {
+		return nil
+	}
+
+	in, args := inPlaceholders(bookIDs)
+
+	query := `DELETE FROM cg_shelf_books WHERE shelf_id = ? AND book_id IN (` + in + `)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, append([]any{shelfID}, args...)...)
+	return err
+}
+
+// Share creates a new share token for the shelf and returns its plaintext. Only
+// the hash is stored, so a previously shared link stops working.
+func (s *ShelfModel) Share(shelf *Shelf) (string, error) {
+	token, err := generateToken(shelf.UserID, 0, "shelf")
+	if err != nil {
+		return "", err
+	}
+
+	query := `UPDATE cg_shelves SET share_hash = ?, updated_at = UTC_TIMESTAMP() WHERE id = ? AND user_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err = s.DB.ExecContext(ctx, query, token.Hash, shelf.ID, shelf.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	return token.Plaintext, nil
+}
+
+// Unshare revokes the shelf's share token.
+func (s *ShelfModel) Unshare(shelf *Shelf) error {
+	query := `UPDATE cg_shelves SET share_hash = NULL, updated_at = UTC_TIMESTAMP() WHERE id = ? AND user_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, shelf.ID, shelf.UserID)
+	return err
+}
+
+// GetShelvesForBook returns the user's shelves the book is on.
+func (s *ShelfModel) GetShelvesForBook(bookID int64, userID int64) ([]*BookShelf, error) {
+	query := `SELECT s.id, s.name, sb.position FROM cg_shelves s
+						INNER JOIN cg_shelf_books sb ON sb.shelf_id = s.id
+						WHERE sb.book_id = ? AND s.user_id = ?
+						ORDER BY s.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, bookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	shelves := []*BookShelf{}
+
+	for rows.Next() {
+		shelf := &BookShelf{}
+
+		err := rows.Scan(&shelf.ID, &shelf.Name, &shelf.Position)
+		if err != nil {
+			return nil, err
+		}
+		shelves = append(shelves, shelf)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shelves, nil
+}
@@ -0,0 +1,255 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+type Tag struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	BooksWithTag int       `json:"books_with_tag"`
+	DateAdded    string    `json:"date_added"`
+	DateUpdated  string    `json:"date_updated"`
+	CreatedAt    time.Time `json:"-"`
+	UpdatedAt    time.Time `json:"-"`
+}
+
+type TagModel struct {
+	DB DBTX
+}
+
+// NormalizeTag trims and lower-cases a tag so "Signed" and " signed" are the same
+// tag.
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// NormalizeTags normalizes every tag in names.
+func NormalizeTags(names []string) []string {
+	tags := make([]string, len(names))
+	for i, name := range names {
+		tags[i] = NormalizeTag(name)
+	}
+
+	return tags
+}
+
+// ValidateTags checks tags that have already been normalized.
+func ValidateTags(v *validator.Validator, tags []string) {
+	for _, tag := range tags {
+		v.Check(tag != "", "tags", "must not contain empty tags")
+		v.Check(len(tag) <= 100, "tags", "must not contain tags longer than 100 bytes")
+		v.Check(!strings.Contains(tag, ","), "tags", "must not contain commas")
+	}
+
+	v.Check(validator.Unique(tags), "tags", "must not contain duplicate values")
+}
+
+// tagCondition returns the WHERE condition for the tags= filter of GetBooks. With
+// matchAll a book needs every tag, otherwise any one of them.
+func tagCondition(tags []string, matchAll bool, args *[]any) string {
+	unique := map[string]bool{}
+	marks := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if unique[tag] {
+			continue
+		}
+		unique[tag] = true
+		marks = append(marks, "?")
+		*args = append(*args, tag)
+	}
+
+	in := strings.Join(marks, ",")
+
+	if matchAll {
+		*args = append(*args, len(marks))
+		return `(SELECT COUNT(DISTINCT t.id) FROM cg_book_tags bt INNER JOIN cg_tags t ON t.id = bt.tag_id WHERE bt.book_id = b.id AND t.name IN (` + in + `)) = ?`
+	}
+
+	return `EXISTS (SELECT 1 FROM cg_book_tags bt INNER JOIN cg_tags t ON t.id = bt.tag_id WHERE bt.book_id = b.id AND t.name IN (` + in + `))`
+}
+
+// EnsureTags returns the ids of the named tags in the library, creating the ones
+// that don't exist yet.
+func (t *TagModel) EnsureTags(libraryID int64, names []string) ([]int64, error) {
+	if len(names) == 0 {
+		return []int64{}, nil
+	}
+
+	query := `INSERT IGNORE INTO cg_tags (library_id, name, created_at, updated_at) VALUES`
+
+	args := []any{}
+
+	for _, name := range names {
+		args = append(args, libraryID, name)
+		query += `(?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP()),`
+	}
+	query = query[:len(query)-1]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	marks := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+
+	args = []any{libraryID}
+	for _, name := range names {
+		args = append(args, name)
+	}
+
+	rows, err := t.DB.QueryContext(ctx, `SELECT id FROM cg_tags WHERE library_id = ? AND name IN (`+marks+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (t *TagModel) InsertBookTags(bookID int64, tagIDs []int64) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO cg_book_tags (book_id, tag_id, created_at, updated_at) VALUES`
+
+	args := []any{}
+
+	for _, tagID := range tagIDs {
+		args = append(args, bookID, tagID)
+		query += `(?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP()),`
+	}
+	query = query[:len(query)-1]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeleteBookTags removes every tag from a book. The tags themselves are kept.
+func (t *TagModel) DeleteBookTags(bookID int64) error {
+	query := `DELETE FROM cg_book_tags WHERE book_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, bookID)
+	return err
+}
+
+// GetTags returns the tags of the library starting with prefix, most used first,
+// for autocompletion. An empty prefix returns every tag.
+func (t *TagModel) GetTags(libraryID int64, prefix string) ([]*Tag, error) {
+	query := `SELECT t.id, t.name, COUNT(bt.book_id), t.created_at, t.updated_at FROM cg_tags t
+						LEFT JOIN cg_book_tags bt ON bt.tag_id = t.id
+						WHERE t.library_id = ? AND t.name LIKE ?
+						GROUP BY t.id
+						ORDER BY COUNT(bt.book_id) DESC, t.name`
+
+	// % and _ in the prefix are matched literally
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(NormalizeTag(prefix)) + "%"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query, libraryID, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tags := []*Tag{}
+
+	for rows.Next() {
+		tag := &Tag{}
+
+		err := rows.Scan(&tag.ID, &tag.Name, &tag.BooksWithTag, &tag.CreatedAt, &tag.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// GetTagsForBooks loads the tag names of every book in ids with a single query,
+// keyed by book id.
+func (t *TagModel) GetTagsForBooks(ids []int64) (map[int64][]string, error) {
+	tags := make(map[int64][]string, len(ids))
+	if len(ids) == 0 {
+		return tags, nil
+	}
+
+	in, args := inPlaceholders(ids)
+
+	query := `SELECT bt.book_id, t.name FROM cg_tags t
+						INNER JOIN cg_book_tags bt ON bt.tag_id = t.id
+						WHERE bt.book_id IN (` + in + `)
+						ORDER BY bt.book_id, t.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookId int64
+		var name string
+
+		err := rows.Scan(&bookId, &name)
+		if err != nil {
+			return nil, err
+		}
+		tags[bookId] = append(tags[bookId], name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,287 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/tklara86/book_catalogue/internal/validator"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrEditConflict   = errors.New("edit conflict")
+)
+
+// AnonymousUser represents a request without a valid authentication token.
+var AnonymousUser = &User{}
+
+type User struct {
+	ID        int64     `json:"id"`
+	LibraryID int64     `json:"library_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Password  password  `json:"-"`
+	Activated bool      `json:"activated"`
+	Version   int       `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// IsAnonymous reports whether the user is the AnonymousUser.
+func (u *User) IsAnonymous() bool {
+	return u == AnonymousUser
+}
+
+type password struct {
+	plaintext *string
+	hash      []byte
+}
+
+// Set hashes the plaintext password with bcrypt and stores both values.
+func (p *password) Set(plaintextPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	if err != nil {
+		return err
+	}
+
+	p.plaintext = &plaintextPassword
+	p.hash = hash
+
+	return nil
+}
+
+// Matches checks whether the plaintext password matches the stored hash.
+func (p *password) Matches(plaintextPassword string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintextPassword))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func ValidateEmail(v *validator.Validator, email string) {
+	v.Check(email != "", "email", "must be provided")
+	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
+}
+
+func ValidatePasswordPlaintext(v *validator.Validator, password string) {
+	v.Check(password != "", "password", "must be provided")
+	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
+	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+}
+
+func ValidateUser(v *validator.Validator, user *User) {
+	v.Check(user.Name != "", "name", "must be provided")
+	v.Check(len(user.Name) <= 255, "name", "must not be more than 255 bytes long")
+
+	ValidateEmail(v, user.Email)
+
+	if user.Password.plaintext != nil {
+		ValidatePasswordPlaintext(v, *user.Password.plaintext)
+	}
+
+	// If the password hash is ever nil, this will be due to a logic error in our
+	// codebase, not a problem with the data the client sent.
+	if user.Password.hash == nil {
+		panic("missing password hash for user")
+	}
+}
+
+type UserModel struct {
+	DB DBTX
+}
+
+// Insert adds a new user and sets its id, returning ErrDuplicateEmail when the
+// address is already registered.
+func (u *UserModel) Insert(user *User) error {
+	query := `INSERT INTO cg_users (library_id, name, email, password_hash, activated, created_at, updated_at) VALUES (?, TRIM(?), LOWER(TRIM(?)), ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	args := []any{user.LibraryID, user.Name, user.Email, user.Password.hash, user.Activated}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := u.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	user.ID = id
+	user.Version = 1
+
+	return nil
+}
+
+func (u *UserModel) Get(id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, library_id, name, email, password_hash, activated, version, created_at, updated_at FROM cg_users WHERE id = ?`
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := u.DB.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.LibraryID, &user.Name, &user.Email, &user.Password.hash, &user.Activated, &user.Version, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+func (u *UserModel) GetByEmail(email string) (*User, error) {
+	query := `SELECT id, library_id, name, email, password_hash, activated, version, created_at, updated_at FROM cg_users WHERE email = LOWER(TRIM(?))`
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := u.DB.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.LibraryID, &user.Name, &user.Email, &user.Password.hash, &user.Activated, &user.Version, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// Update saves the user, using the version column for optimistic locking. If the
+// row was changed since it was read ErrEditConflict is returned.
+func (u *UserModel) Update(user *User) error {
+	query := `UPDATE cg_users SET library_id = ?, name = TRIM(?), email = LOWER(TRIM(?)), password_hash = ?, activated = ?, version = version + 1, updated_at = UTC_TIMESTAMP()
+						WHERE id = ? AND version = ?`
+
+	args := []any{user.LibraryID, user.Name, user.Email, user.Password.hash, user.Activated, user.ID, user.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := u.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	user.Version++
+
+	return nil
+}
+
+// GetForToken returns the user owning a non-expired token with the given scope.
+func (u *UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `SELECT u.id, u.library_id, u.name, u.email, u.password_hash, u.activated, u.version, u.created_at, u.updated_at FROM cg_users u
+						INNER JOIN cg_tokens t ON t.user_id = u.id
+						WHERE t.hash = ? AND t.scope = ? AND t.expiry > UTC_TIMESTAMP()`
+
+	args := []any{tokenHash[:], tokenScope}
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := u.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.LibraryID, &user.Name, &user.Email, &user.Password.hash, &user.Activated, &user.Version, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// GetForLibrary returns the members of a library.
+func (u *UserModel) GetForLibrary(libraryID int64) ([]*User, error) {
+	query := `SELECT id, library_id, name, email, password_hash, activated, version, created_at, updated_at FROM cg_users WHERE library_id = ? ORDER BY name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	rows, err := u.DB.QueryContext(ctx, query, libraryID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []*User{}
+
+	for rows.Next() {
+		user := &User{}
+
+		err := rows.Scan(&user.ID, &user.LibraryID, &user.Name, &user.Email, &user.Password.hash, &user.Activated, &user.Version, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
@@ -0,0 +1,114 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/tklara86/book_catalogue/internal/validator"
+)
+
+// Work is the abstract book ("Dune") that editions in cg_books belong to. Authors
+// and categories are attached to the work and shared by all of its editions.
+type Work struct {
+	ID             int64       `json:"id"`
+	LibraryID      int64       `json:"library_id"`
+	Title          string      `json:"title"`
+	Description    string      `json:"description,omitempty"`
+	WorkAuthors    []*Author   `json:"work_authors,omitempty"`
+	WorkCategories []*Category `json:"work_categories,omitempty"`
+	Editions       []*Book     `json:"editions,omitempty"`
+	DateAdded      string      `json:"date_added"`
+	DateUpdated    string      `json:"date_updated"`
+	CreatedAt      time.Time   `json:"-"`
+	UpdatedAt      time.Time   `json:"-"`
+}
+
+type WorkModel struct {
+	DB DBTX
+}
+
+func ValidateWork(v *validator.Validator, work *Work) {
+
+	v.Check(work.Title != "", "title", "Title cannot be empty")
+	v.Check(len(work.Title) <= 255, "title", "Title must not be more than 255 characters long")
+
+}
+
+func (w *WorkModel) Insert(work *Work) (int, error) {
+	query := `INSERT INTO cg_works (library_id,title,description,created_at,updated_at) VALUES (?, TRIM(?), TRIM(?), UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	result, err := w.DB.ExecContext(ctx, query, work.LibraryID, work.Title, work.Description)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetWork returns the work if it belongs to the library
+func (w *WorkModel) GetWork(id int64, libraryID int64) (*Work, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, library_id, title, COALESCE(description, ''), created_at, updated_at FROM cg_works WHERE id = ? AND library_id = ?`
+
+	var work Work
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := w.DB.QueryRowContext(ctx, query, id, libraryID).Scan(&work.ID, &work.LibraryID, &work.Title, &work.Description, &work.CreatedAt, &work.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &work, nil
+}
+
+func (w *WorkModel) UpdateWork(work *Work) error {
+	query := `UPDATE cg_works SET title = TRIM(?), description = TRIM(?), updated_at = UTC_TIMESTAMP() WHERE id = ? AND library_id = ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := w.DB.ExecContext(ctx, query, work.Title, work.Description, work.ID, work.LibraryID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteUnusedWorks removes the works of the library that have no editions left,
+// e.g. after their last edition was deleted or moved to another work.
+func (w *WorkModel) DeleteUnusedWorks(libraryID int64) error {
+	query := `DELETE cg_works FROM cg_works
+						LEFT JOIN cg_books b ON b.work_id = cg_works.id
+						WHERE cg_works.library_id = ? AND b.id IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	_, err := w.DB.ExecContext(ctx, query, libraryID)
+	return err
+}
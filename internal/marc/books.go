@@ -0,0 +1,322 @@
+package marc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+)
+
+// DefaultLeader is the leader of the records made from books: a new record (n) for
+// a language material (a) monograph (m), in Unicode (a), with the ISBD punctuation
+// left out (c). The lengths and addresses are filled in when it is written.
+const DefaultLeader = "00000nam a2200000 c 4500"
+
+// relatorCodes are the MARC relator codes of the contributor roles.
+var relatorCodes = map[string]string{
+	data.RoleAuthor:      "aut",
+	data.RoleTranslator:  "trl",
+	data.RoleEditor:      "edt",
+	data.RoleIllustrator: "ill",
+	data.RoleNarrator:    "nrt",
+}
+
+// nonfilingArticles are the leading articles skipped when sorting titles, their
+// length goes in the second indicator of 245.
+var nonfilingArticles = []string{"The ", "A ", "An "}
+
+// FromBook makes a record of the book. Its links (authors, categories and
+// publishers) are expected to be loaded. The first author goes in 100 and the
+// other contributors in 700, with their role as relator term and code.
+func FromBook(book *data.Book) *Record {
+	rec := &Record{Leader: DefaultLeader}
+
+	rec.AddControl("001", strconv.FormatInt(book.ID, 10))
+	if !book.UpdatedAt.IsZero() {
+		rec.AddControl("005", book.UpdatedAt.UTC().Format("20060102150405")+".0")
+	}
+	rec.AddControl("008", fixedLengthData(book))
+
+	rec.AddData("020", ' ', ' ', "a", book.ISBN)
+
+	mainEntry := false
+	for _, a := range book.BookAuthors {
+		role := a.Role
+		if role == "" {
+			role = data.RoleAuthor
+		}
+
+		tag := "700"
+		if role == data.RoleAuthor && !mainEntry {
+			tag = "100"
+			mainEntry = true
+		}
+
+		rec.AddData(tag, '1', ' ', "a", invertedName(a), "e", role, "4", relatorCodes[role])
+	}
+
+	titleInd1 := byte('0')
+	if mainEntry {
+		titleInd1 = '1'
+	}
+	rec.AddData("245", titleInd1, nonfiling(book.Title), "a", book.Title, "b", book.Subtitle)
+
+	publication := []string{}
+	for _, p := range book.BookPublishers {
+		publication = append(publication, "b", p.Name)
+	}
+	publication = append(publication, "c", book.PublishedDate)
+	rec.AddData("264", ' ', '1', publication...)
+
+	if book.PageCount > 0 {
+		rec.AddData("300", ' ', ' ', "a", fmt.Sprintf("%d pages", book.PageCount))
+	}
+
+	rec.AddData("520", ' ', ' ', "a", book.Description)
+
+	for _, c := range book.BookCategories {
+		rec.AddData("650", ' ', '4', "a", c.Name)
+	}
+
+	// 100 has to come before 245, and 700 after 650
+	sortFields(rec)
+
+	return rec
+}
+
+// fixedLengthData makes the 40 characters of 008 for a book: the date the record
+// was entered, a single publication year when there is one and an undetermined
+// language.
+func fixedLengthData(book *data.Book) string {
+	f := []byte(strings.Repeat(" ", 40))
+
+	entered := "||||||"
+	if !book.CreatedAt.IsZero() {
+		entered = book.CreatedAt.UTC().Format("060102")
+	}
+	copy(f[0:6], entered)
+
+	if year := yearRX.FindString(book.PublishedDate); year != "" {
+		f[6] = 's'
+		copy(f[7:11], year)
+	} else {
+		f[6] = 'n'
+		copy(f[7:11], "uuuu")
+	}
+
+	copy(f[15:18], "xx ")
+	copy(f[35:38], "und")
+	f[39] = 'd'
+
+	return string(f)
+}
+
+func invertedName(a *data.Author) string {
+	switch {
+	case a.LastName == "":
+		return a.FirstName
+	case a.FirstName == "":
+		return a.LastName
+	default:
+		return a.LastName + ", " + a.FirstName
+	}
+}
+
+func nonfiling(title string) byte {
+	for _, article := range nonfilingArticles {
+		if strings.HasPrefix(title, article) {
+			return byte('0' + len(article))
+		}
+	}
+	return '0'
+}
+
+// sortFields orders the fields by tag, keeping the order of fields with the same
+// tag.
+func sortFields(rec *Record) {
+	fields := rec.Fields
+	for i := 1; i < len(fields); i++ {
+		for j := i; j > 0 && fields[j].Tag < fields[j-1].Tag; j-- {
+			fields[j], fields[j-1] = fields[j-1], fields[j]
+		}
+	}
+}
+
+// Contributor is a name from 100 or 700, inverted ("Last, First") the way MARC
+// records them, with the role read from the relator code or term.
+type Contributor struct {
+	Name string
+	Role string
+}
+
+// BookRecord is what a record says about a book.
+type BookRecord struct {
+	ControlNumber string
+	Title         string
+	Subtitle      string
+	ISBN          string
+	ISBNs         []string
+	Contributors  []Contributor
+	Publishers    []string
+	PublishedDate string
+	PageCount     int
+	Description   string
+	Subjects      []string
+}
+
+var (
+	yearRX   = regexp.MustCompile(`\b\d{4}\b`)
+	numberRX = regexp.MustCompile(`\d+`)
+	isbnRX   = regexp.MustCompile(`^[0-9Xx-]+`)
+)
+
+// ReadBook reads a bibliographic record: 020 for the ISBNs, 100 and 700 for the
+// contributors, 245 for the title, 264 (or the older 260) for the publication,
+// 300 for the page count, 520 for the summary and 650 for the subjects. The ISBD
+// punctuation is taken off the values. Added entries with a relator that isn't one
+// of the contributor roles, such as a writer of an introduction, are left out.
+func ReadBook(rec *Record) *BookRecord {
+	br := &BookRecord{}
+
+	if f := rec.Field("001"); f != nil {
+		br.ControlNumber = strings.TrimSpace(f.Value)
+	}
+
+	for _, f := range rec.FieldsByTag("020") {
+		for _, s := range f.SubfieldValues('a') {
+			isbn := strings.ReplaceAll(isbnRX.FindString(strings.TrimSpace(s)), "-", "")
+			if isbn == "" {
+				continue
+			}
+			if br.ISBN == "" {
+				br.ISBN = strings.ToUpper(isbn)
+			} else {
+				br.ISBNs = append(br.ISBNs, strings.ToUpper(isbn))
+			}
+		}
+	}
+
+	for _, tag := range []string{"100", "700"} {
+		for _, f := range rec.FieldsByTag(tag) {
+			// a 700 with a title is an added entry for another work
+			if f.Subfield('t') != "" {
+				continue
+			}
+
+			name := trimName(f.Subfield('a'))
+			role, ok := relatorRole(f)
+			if name == "" || !ok {
+				continue
+			}
+
+			br.Contributors = append(br.Contributors, Contributor{Name: name, Role: role})
+		}
+	}
+
+	if f := rec.Field("245"); f != nil {
+		br.Title = trimPunctuation(f.Subfield('a'))
+		br.Subtitle = trimPunctuation(f.Subfield('b'))
+	}
+
+	publication := rec.Field("260")
+	for _, f := range rec.FieldsByTag("264") {
+		if f.Ind2 == '1' {
+			publication = f
+			break
+		}
+	}
+	if publication != nil {
+		for _, s := range publication.SubfieldValues('b') {
+			if p := trimPunctuation(s); p != "" {
+				br.Publishers = append(br.Publishers, p)
+			}
+		}
+		br.PublishedDate = strings.Trim(trimPunctuation(publication.Subfield('c')), "[]©℗ ")
+	}
+
+	if f := rec.Field("300"); f != nil {
+		for _, n := range numberRX.FindAllString(f.Subfield('a'), -1) {
+			pages, err := strconv.Atoi(n)
+			if err == nil && pages > br.PageCount {
+				br.PageCount = pages
+			}
+		}
+	}
+
+	if f := rec.Field("520"); f != nil {
+		br.Description = strings.TrimSpace(f.Subfield('a'))
+	}
+
+	seen := map[string]bool{}
+	for _, f := range rec.FieldsByTag("650") {
+		subject := trimPunctuation(f.Subfield('a'))
+		if subject != "" && !seen[strings.ToLower(subject)] {
+			seen[strings.ToLower(subject)] = true
+			br.Subjects = append(br.Subjects, subject)
+		}
+	}
+
+	return br
+}
+
+// relatorRole reads the role from the relator code ($4) or term ($e). Entries
+// without either are authors.
+func relatorRole(f *Field) (string, bool) {
+	codes := f.SubfieldValues('4')
+	terms := f.SubfieldValues('e')
+
+	if len(codes) == 0 && len(terms) == 0 {
+		return data.RoleAuthor, true
+	}
+
+	for _, code := range codes {
+		for role, c := range relatorCodes {
+			if strings.TrimSpace(code) == c {
+				return role, true
+			}
+		}
+	}
+
+	for _, term := range terms {
+		term = strings.ToLower(trimPunctuation(term))
+		if _, ok := relatorCodes[term]; ok {
+			return term, true
+		}
+	}
+
+	return "", false
+}
+
+// trimPunctuation takes off the ISBD punctuation ending a value, " /", " :", " ;",
+// " =", "," and a full stop.
+func trimPunctuation(s string) string {
+	s = strings.TrimSpace(s)
+
+	for {
+		trimmed := strings.TrimSpace(strings.TrimRight(s, "/:;=,"))
+		trimmed = strings.TrimSuffix(trimmed, ".")
+		if trimmed == s {
+			return s
+		}
+		s = trimmed
+	}
+}
+
+// trimName takes the punctuation off a name, keeping the full stop after an
+// initial ("Tolkien, J. R. R.").
+func trimName(s string) string {
+	s = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(s), ",;:"))
+
+	if strings.HasSuffix(s, ".") {
+		words := strings.Fields(s)
+		last := []rune(strings.TrimSuffix(words[len(words)-1], "."))
+		if len(last) != 1 || !unicode.IsUpper(last[0]) {
+			s = strings.TrimSuffix(s, ".")
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,217 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Reader reads records in the ISO 2709 format, one after another.
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next record, or io.EOF when there are no more. Whitespace
+// between records, such as a trailing newline, is skipped.
+func (rd *Reader) Read() (*Record, error) {
+	for {
+		b, err := rd.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != '\n' && b[0] != '\r' && b[0] != ' ' {
+			break
+		}
+		_, _ = rd.r.ReadByte()
+	}
+
+	raw, err := rd.r.ReadBytes(recordTerminator)
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: missing record terminator", ErrInvalidRecord)
+		}
+		return nil, err
+	}
+
+	return Unmarshal(raw)
+}
+
+// ReadAll reads the remaining records.
+func (rd *Reader) ReadAll() ([]*Record, error) {
+	records := []*Record{}
+
+	for {
+		rec, err := rd.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+// Unmarshal decodes one record in the ISO 2709 format. The lengths in the
+// directory are in bytes, the data is expected to be UTF-8.
+func Unmarshal(raw []byte) (*Record, error) {
+	if len(raw) < leaderLength+1 {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidRecord)
+	}
+
+	leader := string(raw[:leaderLength])
+
+	base, ok := parseDigits([]byte(leader[12:17]))
+	if !ok || base <= leaderLength || base > len(raw) {
+		return nil, fmt.Errorf("%w: bad base address of data", ErrInvalidRecord)
+	}
+
+	directory := raw[leaderLength : base-1]
+	if raw[base-1] != fieldTerminator || len(directory)%12 != 0 {
+		return nil, fmt.Errorf("%w: bad directory", ErrInvalidRecord)
+	}
+
+	rec := &Record{Leader: leader}
+	data := raw[base:]
+
+	for i := 0; i < len(directory); i += 12 {
+		entry := directory[i : i+12]
+
+		tag := string(entry[:3])
+		length, ok1 := parseDigits(entry[3:7])
+		start, ok2 := parseDigits(entry[7:12])
+		if !ok1 || !ok2 || length < 1 || start+length > len(data) {
+			return nil, fmt.Errorf("%w: bad directory entry for %s", ErrInvalidRecord, tag)
+		}
+
+		value := bytes.TrimSuffix(data[start:start+length], []byte{fieldTerminator})
+
+		f := &Field{Tag: tag}
+
+		if f.IsControl() {
+			f.Value = string(value)
+			rec.Fields = append(rec.Fields, f)
+			continue
+		}
+
+		if len(value) < 2 {
+			return nil, fmt.Errorf("%w: field %s has no indicators", ErrInvalidRecord, tag)
+		}
+		f.Ind1, f.Ind2 = value[0], value[1]
+
+		for _, sf := range bytes.Split(value[2:], []byte{subfieldDelimiter}) {
+			if len(sf) == 0 {
+				continue
+			}
+			f.Subfields = append(f.Subfields, Subfield{Code: sf[0], Value: string(sf[1:])})
+		}
+
+		rec.Fields = append(rec.Fields, f)
+	}
+
+	return rec, nil
+}
+
+// parseDigits reads a number of the leader or directory. Unlike strconv.Atoi it
+// only takes ASCII digits, so a sign or space can't slip in a negative offset.
+func parseDigits(b []byte) (int, bool) {
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, len(b) > 0
+}
+
+// Marshal encodes the record in the ISO 2709 format, working out the record
+// length, base address and directory. Positions of the leader it doesn't compute
+// are kept from rec.Leader, see DefaultLeader.
+func Marshal(rec *Record) ([]byte, error) {
+	var directory, data bytes.Buffer
+
+	for _, f := range rec.Fields {
+		if len(f.Tag) != 3 {
+			return nil, fmt.Errorf("%w: bad tag %q", ErrInvalidRecord, f.Tag)
+		}
+
+		start := data.Len()
+
+		if f.IsControl() {
+			data.WriteString(f.Value)
+		} else {
+			data.WriteByte(indicator(f.Ind1))
+			data.WriteByte(indicator(f.Ind2))
+			for _, sf := range f.Subfields {
+				data.WriteByte(subfieldDelimiter)
+				data.WriteByte(sf.Code)
+				data.WriteString(sf.Value)
+			}
+		}
+		data.WriteByte(fieldTerminator)
+
+		length := data.Len() - start
+		if length > 9999 || start > 99999 {
+			return nil, fmt.Errorf("%w: field %s is too long", ErrInvalidRecord, f.Tag)
+		}
+
+		fmt.Fprintf(&directory, "%s%04d%05d", f.Tag, length, start)
+	}
+	directory.WriteByte(fieldTerminator)
+	data.WriteByte(recordTerminator)
+
+	base := leaderLength + directory.Len()
+	total := base + data.Len()
+	if total > 99999 {
+		return nil, fmt.Errorf("%w: record is too long", ErrInvalidRecord)
+	}
+
+	leader := []byte(rec.Leader)
+	if len(leader) != leaderLength {
+		leader = []byte(DefaultLeader)
+	}
+	copy(leader[0:5], fmt.Sprintf("%05d", total))
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+	copy(leader[20:24], "4500")
+
+	var out bytes.Buffer
+	out.Write(leader)
+	out.Write(directory.Bytes())
+	out.Write(data.Bytes())
+
+	return out.Bytes(), nil
+}
+
+// Writer writes records in the ISO 2709 format.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (wr *Writer) Write(rec *Record) error {
+	raw, err := Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	_, err = wr.w.Write(raw)
+	return err
+}
+
+// indicator writes an unset indicator as a blank.
+func indicator(b byte) byte {
+	if b == 0 {
+		return ' '
+	}
+	return b
+}
@@ -0,0 +1,107 @@
+// Package marc reads and writes MARC 21 bibliographic records, in the ISO 2709
+// exchange format and as MARCXML, and maps them to and from books.
+package marc
+
+import (
+	"errors"
+	"strings"
+)
+
+// Delimiters of the ISO 2709 format.
+const (
+	subfieldDelimiter = 0x1f
+	fieldTerminator   = 0x1e
+	recordTerminator  = 0x1d
+)
+
+const leaderLength = 24
+
+var ErrInvalidRecord = errors.New("invalid MARC record")
+
+// Record is a MARC record. Control fields (00X) only have a Value, data fields have
+// indicators and subfields.
+type Record struct {
+	Leader string
+	Fields []*Field
+}
+
+type Field struct {
+	Tag       string
+	Value     string
+	Ind1      byte
+	Ind2      byte
+	Subfields []Subfield
+}
+
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+// IsControl reports whether the field is a control field, tags 001 to 009.
+func (f *Field) IsControl() bool {
+	return strings.HasPrefix(f.Tag, "00")
+}
+
+// Subfield returns the first subfield with the code, "" when there isn't one.
+func (f *Field) Subfield(code byte) string {
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			return sf.Value
+		}
+	}
+	return ""
+}
+
+// SubfieldValues returns the values of every subfield with the code.
+func (f *Field) SubfieldValues(code byte) []string {
+	values := []string{}
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			values = append(values, sf.Value)
+		}
+	}
+	return values
+}
+
+// FieldsByTag returns the record's fields with the tag, in order.
+func (r *Record) FieldsByTag(tag string) []*Field {
+	fields := []*Field{}
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Field returns the first field with the tag, nil when there isn't one.
+func (r *Record) Field(tag string) *Field {
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			return f
+		}
+	}
+	return nil
+}
+
+// AddControl adds a control field.
+func (r *Record) AddControl(tag, value string) {
+	r.Fields = append(r.Fields, &Field{Tag: tag, Value: value})
+}
+
+// AddData adds a data field, subfields are given as code and value pairs and the
+// ones with an empty value are left out.
+func (r *Record) AddData(tag string, ind1, ind2 byte, subfields ...string) {
+	f := &Field{Tag: tag, Ind1: ind1, Ind2: ind2}
+
+	for i := 0; i+1 < len(subfields); i += 2 {
+		if subfields[i+1] != "" {
+			f.Subfields = append(f.Subfields, Subfield{Code: subfields[i][0], Value: subfields[i+1]})
+		}
+	}
+
+	if len(f.Subfields) > 0 {
+		r.Fields = append(r.Fields, f)
+	}
+}
@@ -0,0 +1,296 @@
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/tklara86/book_catalogue/internal/data"
+)
+
+// hobbit is what ReadBook should make of testdata/hobbit.mrc and hobbit.xml. The
+// 700 for the writer of the introduction has no contributor role and is left out.
+var hobbit = &BookRecord{
+	ControlNumber: "ocm00012345",
+	Title:         "The hobbit",
+	Subtitle:      "or there and back again",
+	ISBN:          "9780261102217",
+	ISBNs:         []string{"0261102214"},
+	Contributors: []Contributor{
+		{Name: "Tolkien, J. R. R.", Role: data.RoleAuthor},
+		{Name: "Anderson, Douglas A.", Role: data.RoleEditor},
+		{Name: "Lee, Alan", Role: data.RoleIllustrator},
+	},
+	Publishers:    []string{"George Allen & Unwin"},
+	PublishedDate: "1937",
+	PageCount:     310,
+	Description:   "Bilbo Baggins is swept into a quest for the dwarves’ treasure.",
+	Subjects:      []string{"Middle Earth (Imaginary place)", "Fantasy fiction"},
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func readISO2709(t *testing.T, b []byte) []*Record {
+	t.Helper()
+
+	records, err := NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func readMARCXML(t *testing.T, b []byte) []*Record {
+	t.Helper()
+
+	records, err := ReadXML(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func writeISO2709(t *testing.T, records ...*Record) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	wr := NewWriter(&buf)
+	for _, rec := range records {
+		err := wr.Write(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeMARCXML(t *testing.T, records ...*Record) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := WriteXML(&buf, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// formats are the two encodings, each with its fixture.
+var formats = []struct {
+	name    string
+	fixture string
+	read    func(*testing.T, []byte) []*Record
+	write   func(*testing.T, ...*Record) []byte
+}{
+	{"ISO 2709", "hobbit.mrc", readISO2709, writeISO2709},
+	{"MARCXML", "hobbit.xml", readMARCXML, writeMARCXML},
+}
+
+// importBook makes the book the import would store for the record.
+func importBook(br *BookRecord) *data.Book {
+	book := &data.Book{
+		Title:         br.Title,
+		Subtitle:      br.Subtitle,
+		ISBN:          br.ISBN,
+		PageCount:     br.PageCount,
+		PublishedDate: br.PublishedDate,
+		Description:   br.Description,
+	}
+
+	for _, c := range br.Contributors {
+		first, last := data.SplitAuthorName(c.Name)
+		book.BookAuthors = append(book.BookAuthors, &data.Author{FirstName: first, LastName: last, Role: c.Role})
+	}
+	for _, p := range br.Publishers {
+		book.BookPublishers = append(book.BookPublishers, &data.Publisher{Name: p})
+	}
+	for _, s := range br.Subjects {
+		book.BookCategories = append(book.BookCategories, &data.Category{Name: s})
+	}
+
+	return book
+}
+
+func TestReadBookFromFixtures(t *testing.T) {
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			records := format.read(t, readFixture(t, format.fixture))
+			if len(records) != 1 {
+				t.Fatalf("got %d records; want 1", len(records))
+			}
+
+			got := ReadBook(records[0])
+			if !reflect.DeepEqual(got, hobbit) {
+				t.Errorf("got %+v; want %+v", got, hobbit)
+			}
+		})
+	}
+}
+
+func TestFixturesAreTheSameRecord(t *testing.T) {
+	iso := readISO2709(t, readFixture(t, "hobbit.mrc"))
+	xml := readMARCXML(t, readFixture(t, "hobbit.xml"))
+
+	if !reflect.DeepEqual(iso, xml) {
+		t.Errorf("the ISO 2709 record\n%+v\ndiffers from the MARCXML one\n%+v", iso[0], xml[0])
+	}
+}
+
+// Writing a record that was read gives back the same record, and for ISO 2709
+// the same bytes.
+func TestRewriteFixtures(t *testing.T) {
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			fixture := readFixture(t, format.fixture)
+			records := format.read(t, fixture)
+
+			written := format.write(t, records...)
+
+			if format.fixture == "hobbit.mrc" && !bytes.Equal(written, fixture) {
+				t.Errorf("got\n%q\nwant\n%q", written, fixture)
+			}
+
+			reread := format.read(t, written)
+			if !reflect.DeepEqual(reread, records) {
+				t.Errorf("got %+v; want %+v", reread[0], records[0])
+			}
+		})
+	}
+}
+
+// A book exported, imported and exported again gives the same record.
+func TestExportImportExport(t *testing.T) {
+	book := &data.Book{
+		ID:            42,
+		Title:         "The Left Hand of Darkness",
+		ISBN:          "9780441478125",
+		PageCount:     304,
+		PublishedDate: "1969",
+		Description:   "Genly Ai is sent to Gethen, the world called Winter.",
+		BookAuthors: []*data.Author{
+			{FirstName: "Ursula K.", LastName: "Le Guin", Role: data.RoleAuthor},
+			{FirstName: "Charles", LastName: "Vess", Role: data.RoleIllustrator},
+			{FirstName: "Harold", LastName: "Bloom", Role: data.RoleEditor},
+		},
+		BookPublishers: []*data.Publisher{{Name: "Ace Books"}},
+		BookCategories: []*data.Category{{Name: "Science fiction"}, {Name: "Gender identity"}},
+	}
+
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			exported := format.write(t, FromBook(book))
+
+			records := format.read(t, exported)
+			if len(records) != 1 {
+				t.Fatalf("got %d records; want 1", len(records))
+			}
+
+			br := ReadBook(records[0])
+
+			want := &BookRecord{
+				ControlNumber: "42",
+				Title:         book.Title,
+				ISBN:          book.ISBN,
+				Contributors: []Contributor{
+					{Name: "Le Guin, Ursula K.", Role: data.RoleAuthor},
+					{Name: "Vess, Charles", Role: data.RoleIllustrator},
+					{Name: "Bloom, Harold", Role: data.RoleEditor},
+				},
+				Publishers:    []string{"Ace Books"},
+				PublishedDate: "1969",
+				PageCount:     304,
+				Description:   book.Description,
+				Subjects:      []string{"Science fiction", "Gender identity"},
+			}
+			if !reflect.DeepEqual(br, want) {
+				t.Fatalf("imported %+v; want %+v", br, want)
+			}
+
+			imported := importBook(br)
+			imported.ID = book.ID
+
+			reexported := format.write(t, FromBook(imported))
+			if !bytes.Equal(reexported, exported) {
+				t.Errorf("exported again\n%s\nwant\n%s", reexported, exported)
+			}
+		})
+	}
+}
+
+// A record imported, exported and imported again reads the same, apart from what
+// a book doesn't keep: the control number and the other ISBNs.
+func TestImportExportImport(t *testing.T) {
+	want := *hobbit
+	want.ControlNumber = "0"
+	want.ISBNs = nil
+
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			records := format.read(t, readFixture(t, format.fixture))
+
+			exported := format.write(t, FromBook(importBook(ReadBook(records[0]))))
+
+			reimported := format.read(t, exported)
+			if len(reimported) != 1 {
+				t.Fatalf("got %d records; want 1", len(reimported))
+			}
+
+			got := ReadBook(reimported[0])
+			if !reflect.DeepEqual(got, &want) {
+				t.Errorf("got %+v; want %+v", got, &want)
+			}
+
+			// the fields of the record are written the way MARC 21 expects them
+			rec := reimported[0]
+			for tag, want := range map[string]string{"020": "  ", "100": "1 ", "245": "14", "264": " 1", "300": "  ", "650": " 4", "700": "1 "} {
+				f := rec.Field(tag)
+				if f == nil {
+					t.Errorf("no %s field", tag)
+					continue
+				}
+				if got := string([]byte{f.Ind1, f.Ind2}); got != want {
+					t.Errorf("%s has indicators %q; want %q", tag, got, want)
+				}
+			}
+		})
+	}
+}
+
+// Records with numbers that aren't plain digits in the leader or directory are
+// rejected instead of read from the wrong place.
+func TestUnmarshalBadNumbers(t *testing.T) {
+	fixture := readFixture(t, "hobbit.mrc")
+
+	tests := []struct {
+		name  string
+		at    int
+		value string
+	}{
+		{"negative start", 31, "-0001"},
+		{"signed length", 27, "+010"},
+		{"spaces in start", 31, "  000"},
+		{"negative base address", 12, "-0001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := append([]byte(nil), fixture...)
+			copy(raw[tt.at:], tt.value)
+
+			_, err := Unmarshal(raw)
+			if !errors.Is(err, ErrInvalidRecord) {
+				t.Errorf("got error %v; want %v", err, ErrInvalidRecord)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package marc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Namespace is the MARCXML namespace.
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ReadXML reads the records of a MARCXML document, either a collection or a
+// single record. Control fields are placed before the data fields.
+func ReadXML(r io.Reader) ([]*Record, error) {
+	dec := xml.NewDecoder(r)
+
+	records := []*Record{}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var xr xmlRecord
+		err = dec.DecodeElement(&xr, &start)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w: %v", len(records)+1, ErrInvalidRecord, err)
+		}
+
+		records = append(records, xr.record())
+	}
+}
+
+func (xr xmlRecord) record() *Record {
+	rec := &Record{Leader: xr.Leader}
+
+	for _, cf := range xr.ControlFields {
+		rec.AddControl(cf.Tag, cf.Value)
+	}
+
+	for _, df := range xr.DataFields {
+		f := &Field{Tag: df.Tag, Ind1: xmlIndicator(df.Ind1), Ind2: xmlIndicator(df.Ind2)}
+		for _, sf := range df.Subfields {
+			if sf.Code != "" {
+				f.Subfields = append(f.Subfields, Subfield{Code: sf.Code[0], Value: sf.Value})
+			}
+		}
+		rec.Fields = append(rec.Fields, f)
+	}
+
+	return rec
+}
+
+func xmlIndicator(s string) byte {
+	if s == "" {
+		return ' '
+	}
+	return s[0]
+}
+
+// XMLWriter writes records as a MARCXML collection, one after another. Close ends
+// the collection.
+type XMLWriter struct {
+	w       io.Writer
+	enc     *xml.Encoder
+	started bool
+}
+
+func NewXMLWriter(w io.Writer) *XMLWriter {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	return &XMLWriter{w: w, enc: enc}
+}
+
+var collectionStart = xml.StartElement{
+	Name: xml.Name{Local: "collection"},
+	Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Namespace}},
+}
+
+func (xw *XMLWriter) start() error {
+	if xw.started {
+		return nil
+	}
+	xw.started = true
+
+	_, err := io.WriteString(xw.w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	return xw.enc.EncodeToken(collectionStart)
+}
+
+func (xw *XMLWriter) Write(rec *Record) error {
+	err := xw.start()
+	if err != nil {
+		return err
+	}
+
+	xr := xmlRecord{XMLName: xml.Name{Local: "record"}, Leader: rec.Leader}
+
+	for _, f := range rec.Fields {
+		if f.IsControl() {
+			xr.ControlFields = append(xr.ControlFields, xmlControlField{Tag: f.Tag, Value: f.Value})
+			continue
+		}
+
+		df := xmlDataField{Tag: f.Tag, Ind1: string(indicator(f.Ind1)), Ind2: string(indicator(f.Ind2))}
+		for _, sf := range f.Subfields {
+			df.Subfields = append(df.Subfields, xmlSubfield{Code: string(sf.Code), Value: sf.Value})
+		}
+		xr.DataFields = append(xr.DataFields, df)
+	}
+
+	return xw.enc.Encode(xr)
+}
+
+// Close ends the collection, writing an empty one when no records were written.
+func (xw *XMLWriter) Close() error {
+	err := xw.start()
+	if err != nil {
+		return err
+	}
+
+	err = xw.enc.EncodeToken(collectionStart.End())
+	if err != nil {
+		return err
+	}
+
+	err = xw.enc.Flush()
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(xw.w, "\n")
+	return err
+}
+
+// WriteXML writes the records as a MARCXML collection.
+func WriteXML(w io.Writer, records []*Record) error {
+	xw := NewXMLWriter(w)
+
+	for _, rec := range records {
+		err := xw.Write(rec)
+		if err != nil {
+			return err
+		}
+	}
+
+	return xw.Close()
+}
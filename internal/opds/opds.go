@@ -0,0 +1,167 @@
+// Package opds writes OPDS 1.2 catalog feeds, which are Atom feeds, and the
+// OpenSearch description they point to for searching.
+package opds
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Media types of the catalog documents.
+const (
+	NavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	OpenSearchType  = "application/opensearchdescription+xml"
+)
+
+// Link relations used by the feeds.
+const (
+	RelSelf       = "self"
+	RelStart      = "start"
+	RelUp         = "up"
+	RelSearch     = "search"
+	RelSubsection = "subsection"
+	RelAlternate  = "alternate"
+	RelFirst      = "first"
+	RelPrevious   = "previous"
+	RelNext       = "next"
+	RelLast       = "last"
+	RelImage      = "http://opds-spec.org/image"
+	RelThumbnail  = "http://opds-spec.org/image/thumbnail"
+)
+
+// Feed is a navigation or acquisition feed. TotalResults, ItemsPerPage and
+// StartIndex are the OpenSearch paging elements, left out when 0.
+type Feed struct {
+	XMLName         xml.Name `xml:"feed"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	XmlnsDC         string   `xml:"xmlns:dc,attr"`
+	XmlnsOPDS       string   `xml:"xmlns:opds,attr"`
+	XmlnsOpenSearch string   `xml:"xmlns:opensearch,attr"`
+	ID              string   `xml:"id"`
+	Title           string   `xml:"title"`
+	Updated         string   `xml:"updated"`
+	Author          *Person  `xml:"author,omitempty"`
+	Links           []Link   `xml:"link"`
+	TotalResults    int      `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage    int      `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex      int      `xml:"opensearch:startIndex,omitempty"`
+	Entries         []*Entry `xml:"entry"`
+}
+
+// Entry is a navigation entry, with a Content and a subsection link, or a book,
+// with the Dublin Core terms of the publication.
+type Entry struct {
+	ID           string     `xml:"id"`
+	Title        string     `xml:"title"`
+	Updated      string     `xml:"updated"`
+	Authors      []Person   `xml:"author"`
+	Contributors []Person   `xml:"contributor"`
+	Identifiers  []string   `xml:"dc:identifier"`
+	Publishers   []string   `xml:"dc:publisher"`
+	Issued       string     `xml:"dc:issued,omitempty"`
+	Categories   []Category `xml:"category"`
+	Summary      *Text      `xml:"summary,omitempty"`
+	Content      *Text      `xml:"content,omitempty"`
+	Links        []Link     `xml:"link"`
+}
+
+type Person struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type Link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type Category struct {
+	Term   string `xml:"term,attr"`
+	Label  string `xml:"label,attr,omitempty"`
+	Scheme string `xml:"scheme,attr,omitempty"`
+}
+
+// Text is plain text content.
+type Text struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// NewFeed returns a feed with the namespaces set.
+func NewFeed(id, title string, updated time.Time) *Feed {
+	return &Feed{
+		Xmlns:           "http://www.w3.org/2005/Atom",
+		XmlnsDC:         "http://purl.org/dc/terms/",
+		XmlnsOPDS:       "http://opds-spec.org/2010/catalog",
+		XmlnsOpenSearch: "http://a9.com/-/spec/opensearch/1.1/",
+		ID:              id,
+		Title:           title,
+		Updated:         Timestamp(updated),
+	}
+}
+
+// PlainText returns s as text content, nil when it is empty.
+func PlainText(s string) *Text {
+	if s == "" {
+		return nil
+	}
+	return &Text{Type: "text", Value: s}
+}
+
+// Timestamp formats t the way Atom dates are written.
+func Timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// OpenSearchDescription describes how to search the catalog. The template has a
+// {searchTerms} parameter.
+type OpenSearchDescription struct {
+	XMLName        xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	ShortName      string          `xml:"ShortName"`
+	Description    string          `xml:"Description"`
+	InputEncoding  string          `xml:"InputEncoding"`
+	OutputEncoding string          `xml:"OutputEncoding"`
+	URLs           []OpenSearchURL `xml:"Url"`
+}
+
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// NewOpenSearchDescription returns the description of a search returning
+// acquisition feeds from the template.
+func NewOpenSearchDescription(shortName, description, template string) *OpenSearchDescription {
+	return &OpenSearchDescription{
+		Xmlns:          "http://a9.com/-/spec/opensearch/1.1/",
+		ShortName:      shortName,
+		Description:    description,
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URLs:           []OpenSearchURL{{Type: AcquisitionType, Template: template}},
+	}
+}
+
+// Write writes a feed or an OpenSearch description as an XML document.
+func Write(w io.Writer, doc any) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
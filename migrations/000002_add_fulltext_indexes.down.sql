@@ -0,0 +1,3 @@
+DROP INDEX `cg_categories_fulltext` ON `cg_categories`;
+DROP INDEX `cg_authors_fulltext` ON `cg_authors`;
+DROP INDEX `cg_books_fulltext` ON `cg_books`;
@@ -0,0 +1,3 @@
+CREATE FULLTEXT INDEX `cg_books_fulltext` ON `cg_books` (`title`, `subtitle`, `description`, `isbn`);
+CREATE FULLTEXT INDEX `cg_authors_fulltext` ON `cg_authors` (`first_name`, `last_name`, `description`);
+CREATE FULLTEXT INDEX `cg_categories_fulltext` ON `cg_categories` (`name`);
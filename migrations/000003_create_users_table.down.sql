@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cg_tokens;
+DROP TABLE IF EXISTS cg_users;
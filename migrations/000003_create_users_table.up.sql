@@ -0,0 +1,22 @@
+-- users
+CREATE TABLE IF NOT EXISTS `cg_users` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `email` varchar(255) NOT NULL,
+  `password_hash` varbinary(60) NOT NULL,
+  `activated` bool NOT NULL DEFAULT false,
+  `version` int NOT NULL DEFAULT 1,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+-- tokens
+CREATE TABLE IF NOT EXISTS `cg_tokens` (
+  `hash` binary(32) PRIMARY KEY NOT NULL,
+  `user_id` int NOT NULL,
+  `expiry` datetime NOT NULL,
+  `scope` varchar(255) NOT NULL
+);
+
+CREATE UNIQUE INDEX `cg_users_email_unique` ON `cg_users` (`email`);
+
+ALTER TABLE `cg_tokens` ADD FOREIGN KEY (`user_id`) REFERENCES `cg_users` (`id`) ON DELETE CASCADE;
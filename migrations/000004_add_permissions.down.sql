@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cg_users_permissions;
+DROP TABLE IF EXISTS cg_permissions;
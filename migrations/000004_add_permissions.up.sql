@@ -0,0 +1,19 @@
+-- permissions
+CREATE TABLE IF NOT EXISTS `cg_permissions` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `code` varchar(255) NOT NULL
+);
+-- users permissions
+CREATE TABLE IF NOT EXISTS `cg_users_permissions` (
+  `user_id` int NOT NULL,
+  `permission_id` int NOT NULL,
+  PRIMARY KEY (`user_id`, `permission_id`)
+);
+
+CREATE UNIQUE INDEX `cg_permissions_code_unique` ON `cg_permissions` (`code`);
+
+ALTER TABLE `cg_users_permissions` ADD FOREIGN KEY (`user_id`) REFERENCES `cg_users` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_users_permissions` ADD FOREIGN KEY (`permission_id`) REFERENCES `cg_permissions` (`id`) ON DELETE CASCADE;
+
+INSERT INTO `cg_permissions` (`code`)
+VALUES ('books:read'), ('books:write'), ('authors:write'), ('categories:write'), ('admin');
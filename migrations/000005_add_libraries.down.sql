@@ -0,0 +1,19 @@
+DELETE FROM `cg_permissions` WHERE `code` = 'reading:write';
+
+ALTER TABLE `cg_books` ADD COLUMN `status` ENUM('Not Read','In progress','Read') NOT NULL DEFAULT 'Not Read';
+ALTER TABLE `cg_books` ADD COLUMN `status_id` int NOT NULL DEFAULT 0;
+
+-- keep the furthest status any user reached
+UPDATE `cg_books` b
+  INNER JOIN (SELECT book_id, MAX(status + 0) AS status, MAX(status_id) AS status_id FROM `cg_user_books` GROUP BY book_id) ub ON ub.book_id = b.id
+  SET b.status = ub.status, b.status_id = ub.status_id;
+
+DROP TABLE IF EXISTS cg_user_books;
+
+ALTER TABLE `cg_books` DROP FOREIGN KEY `cg_books_ibfk_1`;
+ALTER TABLE `cg_users` DROP FOREIGN KEY `cg_users_ibfk_1`;
+DROP INDEX `cg_books_library_index` ON `cg_books`;
+ALTER TABLE `cg_books` DROP COLUMN `library_id`;
+ALTER TABLE `cg_users` DROP COLUMN `library_id`;
+
+DROP TABLE IF EXISTS cg_libraries;
@@ -0,0 +1,51 @@
+-- libraries
+CREATE TABLE IF NOT EXISTS `cg_libraries` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+-- per-user reading status
+CREATE TABLE IF NOT EXISTS `cg_user_books` (
+  `user_id` int NOT NULL,
+  `book_id` int NOT NULL,
+  `status` ENUM('Not Read','In progress','Read') NOT NULL DEFAULT 'Not Read',
+  `status_id` int NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now()),
+  PRIMARY KEY (`user_id`, `book_id`)
+);
+
+-- everything catalogued so far belongs to one shared library
+INSERT INTO `cg_libraries` (`name`) VALUES ('Shared library');
+SET @shared_library_id = LAST_INSERT_ID();
+
+ALTER TABLE `cg_books` ADD COLUMN `library_id` int;
+UPDATE `cg_books` SET `library_id` = @shared_library_id;
+ALTER TABLE `cg_books` MODIFY `library_id` int NOT NULL;
+
+ALTER TABLE `cg_users` ADD COLUMN `library_id` int;
+UPDATE `cg_users` SET `library_id` = @shared_library_id;
+ALTER TABLE `cg_users` MODIFY `library_id` int NOT NULL;
+
+-- every existing user starts from the single status the book had
+INSERT INTO `cg_user_books` (`user_id`, `book_id`, `status`, `status_id`)
+SELECT u.id, b.id, b.status, b.status_id FROM `cg_books` b CROSS JOIN `cg_users` u WHERE b.status <> 'Not Read';
+
+ALTER TABLE `cg_books` DROP COLUMN `status`, DROP COLUMN `status_id`;
+
+CREATE INDEX `cg_books_library_index` ON `cg_books` (`library_id`);
+
+ALTER TABLE `cg_books` ADD FOREIGN KEY (`library_id`) REFERENCES `cg_libraries` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_users` ADD FOREIGN KEY (`library_id`) REFERENCES `cg_libraries` (`id`);
+
+ALTER TABLE `cg_user_books` ADD FOREIGN KEY (`user_id`) REFERENCES `cg_users` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_user_books` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
+
+-- reading:write covers a user's own reading data, every existing user keeps
+-- managing their own
+INSERT INTO `cg_permissions` (`code`) VALUES ('reading:write');
+
+INSERT INTO `cg_users_permissions` (`user_id`, `permission_id`)
+SELECT u.`id`, p.`id` FROM `cg_users` u
+INNER JOIN `cg_permissions` p ON p.`code` = 'reading:write';
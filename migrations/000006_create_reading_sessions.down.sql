@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cg_status_history;
+DROP TABLE IF EXISTS cg_reading_sessions;
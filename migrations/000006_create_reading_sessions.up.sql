@@ -0,0 +1,29 @@
+-- reading sessions
+CREATE TABLE IF NOT EXISTS `cg_reading_sessions` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `book_id` int NOT NULL,
+  `started_at` datetime NOT NULL,
+  `ended_at` datetime NOT NULL,
+  `start_page` int NOT NULL DEFAULT 0,
+  `end_page` int NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+-- status history
+CREATE TABLE IF NOT EXISTS `cg_status_history` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `book_id` int NOT NULL,
+  `status` ENUM('Not Read','In progress','Read') NOT NULL,
+  `changed_at` datetime NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX `cg_reading_sessions_user_book_index` ON `cg_reading_sessions` (`user_id`, `book_id`);
+CREATE INDEX `cg_status_history_user_book_index` ON `cg_status_history` (`user_id`, `book_id`);
+
+ALTER TABLE `cg_reading_sessions` ADD FOREIGN KEY (`user_id`) REFERENCES `cg_users` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_reading_sessions` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `cg_status_history` ADD FOREIGN KEY (`user_id`) REFERENCES `cg_users` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_status_history` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,18 @@
+-- reviews
+CREATE TABLE IF NOT EXISTS `cg_reviews` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `book_id` int NOT NULL,
+  `rating` decimal(2,1) NOT NULL,
+  `review` TEXT,
+  `spoiler` bool NOT NULL DEFAULT false,
+  `date_finished` date,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX `cg_reviews_user_book_unique` ON `cg_reviews` (`user_id`, `book_id`);
+CREATE INDEX `cg_reviews_book_index` ON `cg_reviews` (`book_id`);
+
+ALTER TABLE `cg_reviews` ADD FOREIGN KEY (`user_id`) REFERENCES `cg_users` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_reviews` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cg_book_series;
+DROP TABLE IF EXISTS cg_series;
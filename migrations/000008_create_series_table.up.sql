@@ -0,0 +1,26 @@
+-- series
+CREATE TABLE IF NOT EXISTS `cg_series` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `library_id` int NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `description` text,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+-- book series
+CREATE TABLE IF NOT EXISTS `cg_book_series` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `book_id` int,
+  `series_id` int,
+  `position` decimal(6,2) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX `cg_series_library_index` ON `cg_series` (`library_id`);
+CREATE INDEX `cg_book_series_position_index` ON `cg_book_series` (`series_id`, `position`);
+
+ALTER TABLE `cg_series` ADD FOREIGN KEY (`library_id`) REFERENCES `cg_libraries` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `cg_book_series` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_book_series` ADD FOREIGN KEY (`series_id`) REFERENCES `cg_series` (`id`) ON DELETE CASCADE;
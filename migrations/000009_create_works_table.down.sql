@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS `cg_book_authors` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `book_id` int,
+  `author_id` int,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE IF NOT EXISTS `cg_book_categories` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `book_id` int,
+  `category_id` int,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+
+-- every edition gets the authors and categories of its work
+INSERT INTO `cg_book_authors` (`book_id`, `author_id`, `created_at`, `updated_at`)
+SELECT b.id, wa.author_id, wa.created_at, wa.updated_at FROM `cg_work_authors` wa INNER JOIN `cg_books` b ON b.work_id = wa.work_id;
+
+INSERT INTO `cg_book_categories` (`book_id`, `category_id`, `created_at`, `updated_at`)
+SELECT b.id, wc.category_id, wc.created_at, wc.updated_at FROM `cg_work_categories` wc INNER JOIN `cg_books` b ON b.work_id = wc.work_id;
+
+ALTER TABLE `cg_book_authors` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_book_authors` ADD FOREIGN KEY (`author_id`) REFERENCES `cg_authors` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `cg_book_categories` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_book_categories` ADD FOREIGN KEY (`category_id`) REFERENCES `cg_categories` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `cg_books` DROP FOREIGN KEY `cg_books_work_fk`;
+DROP INDEX `cg_books_work_index` ON `cg_books`;
+ALTER TABLE `cg_books` DROP COLUMN `work_id`;
+
+DROP TABLE IF EXISTS `cg_work_categories`;
+DROP TABLE IF EXISTS `cg_work_authors`;
+DROP TABLE IF EXISTS `cg_works`;
@@ -0,0 +1,56 @@
+-- works group the editions (cg_books rows) of the same book
+CREATE TABLE IF NOT EXISTS `cg_works` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `library_id` int NOT NULL,
+  `title` varchar(255) NOT NULL,
+  `description` TEXT,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+-- work authors, with the role they had in the work
+CREATE TABLE IF NOT EXISTS `cg_work_authors` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `work_id` int,
+  `author_id` int,
+  `role` ENUM('author','translator','editor','illustrator','narrator') NOT NULL DEFAULT 'author',
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+-- work categories
+CREATE TABLE IF NOT EXISTS `cg_work_categories` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `work_id` int,
+  `category_id` int,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+
+-- every existing book becomes the only edition of a work with the same id
+INSERT INTO `cg_works` (`id`, `library_id`, `title`, `description`, `created_at`, `updated_at`)
+SELECT id, library_id, title, description, created_at, updated_at FROM `cg_books`;
+
+ALTER TABLE `cg_books` ADD COLUMN `work_id` int;
+UPDATE `cg_books` SET `work_id` = `id`;
+ALTER TABLE `cg_books` MODIFY `work_id` int NOT NULL;
+
+INSERT INTO `cg_work_authors` (`work_id`, `author_id`, `created_at`, `updated_at`)
+SELECT b.work_id, ba.author_id, ba.created_at, ba.updated_at FROM `cg_book_authors` ba INNER JOIN `cg_books` b ON b.id = ba.book_id;
+
+INSERT INTO `cg_work_categories` (`work_id`, `category_id`, `created_at`, `updated_at`)
+SELECT b.work_id, bc.category_id, bc.created_at, bc.updated_at FROM `cg_book_categories` bc INNER JOIN `cg_books` b ON b.id = bc.book_id;
+
+DROP TABLE IF EXISTS `cg_book_authors`;
+DROP TABLE IF EXISTS `cg_book_categories`;
+
+CREATE INDEX `cg_works_library_index` ON `cg_works` (`library_id`);
+CREATE INDEX `cg_books_work_index` ON `cg_books` (`work_id`);
+CREATE INDEX `cg_work_authors_author_index` ON `cg_work_authors` (`author_id`, `role`);
+
+ALTER TABLE `cg_works` ADD FOREIGN KEY (`library_id`) REFERENCES `cg_libraries` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_books` ADD CONSTRAINT `cg_books_work_fk` FOREIGN KEY (`work_id`) REFERENCES `cg_works` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `cg_work_authors` ADD FOREIGN KEY (`work_id`) REFERENCES `cg_works` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_work_authors` ADD FOREIGN KEY (`author_id`) REFERENCES `cg_authors` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `cg_work_categories` ADD FOREIGN KEY (`work_id`) REFERENCES `cg_works` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_work_categories` ADD FOREIGN KEY (`category_id`) REFERENCES `cg_categories` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+ALTER TABLE `cg_categories` DROP FOREIGN KEY `cg_categories_parent_fk`;
+DROP INDEX `cg_categories_parent_index` ON `cg_categories`;
+ALTER TABLE `cg_categories` DROP COLUMN `parent_id`;
@@ -0,0 +1,6 @@
+ALTER TABLE `cg_categories` ADD COLUMN `parent_id` int AFTER `id`;
+
+CREATE INDEX `cg_categories_parent_index` ON `cg_categories` (`parent_id`);
+
+-- children of a deleted category move up to the top level
+ALTER TABLE `cg_categories` ADD CONSTRAINT `cg_categories_parent_fk` FOREIGN KEY (`parent_id`) REFERENCES `cg_categories` (`id`) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cg_book_tags;
+DROP TABLE IF EXISTS cg_tags;
@@ -0,0 +1,25 @@
+-- free-form tags, created on first use in a library
+CREATE TABLE IF NOT EXISTS `cg_tags` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `library_id` int NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now()),
+  UNIQUE KEY `cg_tags_library_name_unique` (`library_id`, `name`)
+);
+-- book tags
+CREATE TABLE IF NOT EXISTS `cg_book_tags` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `book_id` int NOT NULL,
+  `tag_id` int NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now()),
+  UNIQUE KEY `cg_book_tags_unique` (`book_id`, `tag_id`)
+);
+
+CREATE INDEX `cg_book_tags_tag_index` ON `cg_book_tags` (`tag_id`);
+
+ALTER TABLE `cg_tags` ADD FOREIGN KEY (`library_id`) REFERENCES `cg_libraries` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `cg_book_tags` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_book_tags` ADD FOREIGN KEY (`tag_id`) REFERENCES `cg_tags` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cg_shelf_books;
+DROP TABLE IF EXISTS cg_shelves;
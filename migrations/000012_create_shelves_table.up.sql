@@ -0,0 +1,29 @@
+-- shelves are a user's own ordered reading lists
+CREATE TABLE IF NOT EXISTS `cg_shelves` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `description` text,
+  `share_hash` binary(32),
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now()),
+  UNIQUE KEY `cg_shelves_share_hash_unique` (`share_hash`)
+);
+-- shelf books
+CREATE TABLE IF NOT EXISTS `cg_shelf_books` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `shelf_id` int NOT NULL,
+  `book_id` int NOT NULL,
+  `position` int NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now()),
+  UNIQUE KEY `cg_shelf_books_unique` (`shelf_id`, `book_id`)
+);
+
+CREATE INDEX `cg_shelves_user_index` ON `cg_shelves` (`user_id`);
+CREATE INDEX `cg_shelf_books_book_index` ON `cg_shelf_books` (`book_id`);
+
+ALTER TABLE `cg_shelves` ADD FOREIGN KEY (`user_id`) REFERENCES `cg_users` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `cg_shelf_books` ADD FOREIGN KEY (`shelf_id`) REFERENCES `cg_shelves` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_shelf_books` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS cg_loans;
@@ -0,0 +1,20 @@
+-- loans of physical copies
+CREATE TABLE IF NOT EXISTS `cg_loans` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `book_id` int NOT NULL,
+  `borrower_name` varchar(255) NOT NULL,
+  `borrower_contact` varchar(255),
+  `lent_at` date NOT NULL,
+  `due_at` date,
+  `returned_at` date,
+  -- only set while the book is out, so the unique key allows one open loan per book
+  `open_book_id` int GENERATED ALWAYS AS (IF(`returned_at` IS NULL, `book_id`, NULL)) STORED,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now()),
+  UNIQUE KEY `cg_loans_open_book_unique` (`open_book_id`)
+);
+
+CREATE INDEX `cg_loans_book_index` ON `cg_loans` (`book_id`);
+CREATE INDEX `cg_loans_due_index` ON `cg_loans` (`returned_at`, `due_at`);
+
+ALTER TABLE `cg_loans` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
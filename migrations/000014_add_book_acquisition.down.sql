@@ -0,0 +1,10 @@
+DROP INDEX `cg_books_purchase_date_index` ON `cg_books`;
+DROP INDEX `cg_books_ownership_index` ON `cg_books`;
+
+ALTER TABLE `cg_books`
+  DROP COLUMN `vendor`,
+  DROP COLUMN `currency`,
+  DROP COLUMN `price`,
+  DROP COLUMN `purchase_date`,
+  DROP COLUMN `format`,
+  DROP COLUMN `ownership`;
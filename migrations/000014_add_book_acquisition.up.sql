@@ -0,0 +1,11 @@
+-- books already in the catalogue are owned
+ALTER TABLE `cg_books`
+  ADD COLUMN `ownership` ENUM('wishlist','owned','sold','given_away') NOT NULL DEFAULT 'owned' AFTER `isbn`,
+  ADD COLUMN `format` ENUM('hardcover','paperback','ebook','audiobook') AFTER `ownership`,
+  ADD COLUMN `purchase_date` date AFTER `format`,
+  ADD COLUMN `price` decimal(10,2) AFTER `purchase_date`,
+  ADD COLUMN `currency` char(3) AFTER `price`,
+  ADD COLUMN `vendor` varchar(255) AFTER `currency`;
+
+CREATE INDEX `cg_books_ownership_index` ON `cg_books` (`library_id`, `ownership`);
+CREATE INDEX `cg_books_purchase_date_index` ON `cg_books` (`library_id`, `purchase_date`);
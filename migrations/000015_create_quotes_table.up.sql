@@ -0,0 +1,20 @@
+-- quotes and highlights
+CREATE TABLE IF NOT EXISTS `cg_quotes` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `book_id` int NOT NULL,
+  `text` TEXT NOT NULL,
+  `page` int,
+  `location` varchar(50),
+  `note` TEXT,
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX `cg_quotes_user_book_index` ON `cg_quotes` (`user_id`, `book_id`);
+CREATE INDEX `cg_quotes_book_index` ON `cg_quotes` (`book_id`);
+CREATE FULLTEXT INDEX `cg_quotes_fulltext` ON `cg_quotes` (`text`, `note`);
+
+-- quotes go with their book or user, like the author and category links
+ALTER TABLE `cg_quotes` ADD FOREIGN KEY (`user_id`) REFERENCES `cg_users` (`id`) ON DELETE CASCADE;
+ALTER TABLE `cg_quotes` ADD FOREIGN KEY (`book_id`) REFERENCES `cg_books` (`id`) ON DELETE CASCADE;
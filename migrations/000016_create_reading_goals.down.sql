@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS cg_reading_goals;
+
+DROP INDEX `cg_user_books_finished_index` ON `cg_user_books`;
+ALTER TABLE `cg_user_books` DROP COLUMN `finished_at`;
@@ -0,0 +1,26 @@
+-- when the book was last marked Read, set and cleared together with the status
+ALTER TABLE `cg_user_books` ADD COLUMN `finished_at` datetime AFTER `status_id`;
+
+-- books already read finished when their status last changed to Read
+UPDATE `cg_user_books` ub
+SET ub.`finished_at` = COALESCE(
+  (SELECT MAX(h.`changed_at`) FROM `cg_status_history` h WHERE h.`user_id` = ub.`user_id` AND h.`book_id` = ub.`book_id` AND h.`status` = 'Read'),
+  ub.`updated_at`)
+WHERE ub.`status` = 'Read';
+
+CREATE INDEX `cg_user_books_finished_index` ON `cg_user_books` (`user_id`, `finished_at`);
+
+-- yearly reading goals
+CREATE TABLE IF NOT EXISTS `cg_reading_goals` (
+  `id` int PRIMARY KEY NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `year` smallint NOT NULL,
+  `target` int NOT NULL,
+  `unit` ENUM('books','pages') NOT NULL DEFAULT 'books',
+  `created_at` datetime NOT NULL DEFAULT (now()),
+  `updated_at` datetime NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX `cg_reading_goals_user_year_unique` ON `cg_reading_goals` (`user_id`, `year`);
+
+ALTER TABLE `cg_reading_goals` ADD FOREIGN KEY (`user_id`) REFERENCES `cg_users` (`id`) ON DELETE CASCADE;